@@ -1,11 +1,17 @@
 package belajar_go_lang_gorm
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type Address struct {
 	ID        int64 `gorm:"primary_key;column:id"`
 	UserId    string `gorm:"column:user_id"`
-	Address   string  `gorm:"column:address"`
+	// alamat termasuk data pribadi, sehingga di enkripsi (lihat encryption.go)
+	// ciphertext (base64) lebih panjang dari plaintext, sehingga kolom address menggunakan tipe text
+	// untuk tabel lama, AutoMigrate akan mengubah kolom varchar menjadi text
+	Address   string  `gorm:"column:address;type:text;serializer:encrypted"`
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 
@@ -15,6 +21,19 @@ type Address struct {
 	User User `gorm:"foreignKey:user_id;references:id"`
 }
 
+// implementasi validasi, digunakan oleh importer sebelum data di simpan
+func (a *Address) Validate() error {
+	if a.UserId == "" {
+		return errors.New("user_id is required")
+	}
+
+	if a.Address == "" {
+		return errors.New("address is required")
+	}
+
+	return nil
+}
+
 // menentukan nama table
 func (w Address) TableName() string {
 	return "addresses"
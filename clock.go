@@ -0,0 +1,66 @@
+package belajar_go_lang_gorm
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// implementasi clock yang bisa di ganti (inject)
+// semua waktu yang di isi oleh gorm (autoCreateTime, autoUpdateTime, termasuk mode milli)-
+// diambil dari gorm.Config.NowFunc, dan hook pada model juga menggunakan db.NowFunc()-
+// sehingga dengan mengganti clock, semua waktu bisa di kontrol ketika pengujian
+type Clock interface {
+	Now() time.Time
+}
+
+// clock asli menggunakan waktu sistem (sama seperti default gorm)
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now().Local()
+}
+
+var SystemClock Clock = systemClock{}
+
+// menggunakan clock untuk semua operasi pada session db yang dikembalikan
+// contoh : WithClock(db, NewFakeClock(waktu)).Create(&user)
+// untuk seluruh koneksi, isi gorm.Config{NowFunc: clock.Now} ketika gorm.Open()
+func WithClock(db *gorm.DB, clock Clock) *gorm.DB {
+	return db.Session(&gorm.Session{NowFunc: clock.Now})
+}
+
+// clock palsu untuk pengujian, waktu hanya berubah ketika Set() atau Advance() dipanggil
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// mengubah waktu clock
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = now
+}
+
+// memajukan waktu clock sebanyak duration
+func (c *FakeClock) Advance(duration time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(duration)
+	return c.now
+}
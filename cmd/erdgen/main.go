@@ -0,0 +1,59 @@
+// command untuk membuat ER diagram dan data dictionary dari model gorm (lihat Models())
+//
+// contoh :
+//
+//	go run ./cmd/erdgen -format mermaid -out docs/erd.mmd
+//	go run ./cmd/erdgen -format dot | dot -Tpng -o erd.png
+//	go run ./cmd/erdgen -format markdown -out docs/data-dictionary.md
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"belajar-go-lang-gorm/erd"
+	"gorm.io/gorm/schema"
+)
+
+func main() {
+	format := flag.String("format", "mermaid", "output format: mermaid, dot or markdown")
+	out := flag.String("out", "", "output file (default stdout)")
+	flag.Parse()
+
+	err := run(*format, *out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(format string, out string) error {
+	diagram, err := erd.Parse(schema.NamingStrategy{}, belajar_go_lang_gorm.Models()...)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if out != "" {
+		file, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	switch format {
+	case "mermaid":
+		return diagram.WriteMermaid(w)
+	case "dot":
+		return diagram.WriteGraphviz(w)
+	case "markdown":
+		return diagram.WriteMarkdown(w)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+}
@@ -0,0 +1,90 @@
+// command untuk menjalankan EXPLAIN pada sebuah query dan menampilkan peringatan full table scan, filesort dan temporary table
+//
+// query dibaca dari argument, atau dari stdin jika tidak ada argument
+//
+// exit code 0 jika tidak ada peringatan, 1 jika ada peringatan, dan 2 jika terjadi error
+//
+// contoh :
+//
+//	go run ./cmd/explain "SELECT * FROM users ORDER BY first_name"
+//	go run ./cmd/explain -format json -allow filesort < query.sql
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"belajar-go-lang-gorm/explain"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", "mysql dsn")
+	format := flag.String("format", "text", "plan format: text or json")
+	allow := flag.String("allow", "", "comma separated warnings to ignore: full_table_scan, filesort, temporary_table")
+	flag.Parse()
+
+	code, err := run(*dsn, *format, *allow, flag.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(code)
+}
+
+func run(dsn string, format string, allow string, args []string) (int, error) {
+	query := strings.Join(args, " ")
+	if query == "" {
+		input, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return 0, err
+		}
+		query = string(input)
+	}
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+
+	var allowed []explain.WarningKind
+	for _, kind := range strings.Split(allow, ",") {
+		kind = strings.TrimSpace(kind)
+		switch explain.WarningKind(kind) {
+		case "":
+		case explain.FullTableScan, explain.Filesort, explain.TemporaryTable:
+			allowed = append(allowed, explain.WarningKind(kind))
+		default:
+			return 0, fmt.Errorf("unknown warning %q", kind)
+		}
+	}
+
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return 0, err
+	}
+
+	plan, err := explain.ExplainSQL(context.Background(), db, query)
+	if err != nil {
+		return 0, err
+	}
+
+	switch format {
+	case "text":
+		err = plan.WriteText(os.Stdout)
+	case "json":
+		err = plan.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if plan.HasWarnings(allowed...) {
+		return 1, nil
+	}
+	return 0, nil
+}
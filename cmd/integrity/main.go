@@ -0,0 +1,93 @@
+// command untuk memeriksa integritas referensial data (orphan, duplikat, dsb) berdasarkan relasi model
+//
+// exit code 0 jika tidak ada masalah (atau semua berhasil diperbaiki), 1 jika ada masalah, dan 2 jika terjadi error
+//
+// contoh :
+//
+//	go run ./cmd/integrity
+//	go run ./cmd/integrity -format json > integrity.json
+//	go run ./cmd/integrity -repair -batch 200
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"belajar-go-lang-gorm/integrity"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", "mysql dsn")
+	format := flag.String("format", "text", "report format: text or json")
+	repair := flag.Bool("repair", false, "execute the repair plan")
+	batch := flag.Int("batch", 500, "foreign key values per repair transaction")
+	flag.Parse()
+
+	code, err := run(*dsn, *format, *repair, *batch)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(code)
+}
+
+func run(dsn string, format string, repair bool, batch int) (int, error) {
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	report, err := integrity.Scan(ctx, db, belajar_go_lang_gorm.Models()...)
+	if err != nil {
+		return 0, err
+	}
+
+	switch format {
+	case "text":
+		err = report.WriteText(os.Stdout)
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", format)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !report.HasIssues() {
+		return 0, nil
+	}
+	if !repair {
+		return 1, nil
+	}
+
+	plan := integrity.Plan(report)
+	err = plan.WriteText(os.Stderr)
+	if err != nil {
+		return 0, err
+	}
+
+	results, err := plan.Execute(ctx, db, batch)
+	for _, result := range results {
+		fmt.Fprintf(os.Stderr, "%s %s: %d rows in %d batches\n", result.Step.Action, result.Step.Issue.Relation, result.Rows, result.Batches)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// masalah yang harus diperbaiki manual masih tersisa
+	for _, step := range plan.Steps {
+		if step.Action == integrity.RepairManual {
+			return 1, nil
+		}
+	}
+
+	return 0, nil
+}
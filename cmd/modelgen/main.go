@@ -0,0 +1,66 @@
+// command untuk membuat struct model gorm dari tabel yang sudah ada di database
+//
+// file <tabel>_gen.go selalu di tulis ulang, method tambahan ditulis di file lain agar tidak hilang
+//
+// contoh :
+//
+//	go run ./cmd/modelgen -dir ./models
+//	go run ./cmd/modelgen -dir ./models -package models -tables users,wallets
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"belajar-go-lang-gorm/modelgen"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", "mysql dsn")
+	dir := flag.String("dir", ".", "output directory")
+	pkg := flag.String("package", "", "package name (default directory name)")
+	tables := flag.String("tables", "", "comma separated table names (default all tables)")
+	flag.Parse()
+
+	err := run(*dsn, *dir, *pkg, *tables)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(dsn string, dir string, pkg string, tables string) error {
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return err
+	}
+
+	opts := modelgen.Options{Dir: dir, Package: pkg}
+	if tables != "" {
+		opts.Tables = strings.Split(tables, ",")
+	}
+
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+
+	result, err := modelgen.Generate(db, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range result.Written {
+		fmt.Println("written", path)
+	}
+	for _, table := range result.Skipped {
+		fmt.Println("skipped", table, "(hand-written)")
+	}
+
+	return nil
+}
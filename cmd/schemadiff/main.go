@@ -0,0 +1,56 @@
+// command untuk mendeteksi perbedaan schema model dengan database (schema drift)
+//
+// exit code 0 jika tidak ada perbedaan, 1 jika ada perbedaan, dan 2 jika terjadi error
+//
+// contoh :
+//
+//	go run ./cmd/schemadiff
+//	go run ./cmd/schemadiff -format json > drift.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"belajar-go-lang-gorm/schemadiff"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", "mysql dsn")
+	format := flag.String("format", "text", "report format: text or json")
+	flag.Parse()
+
+	db, err := gorm.Open(mysql.Open(*dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	report, err := schemadiff.Compare(db, belajar_go_lang_gorm.Models()...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	switch *format {
+	case "text":
+		err = report.WriteText(os.Stdout)
+	case "json":
+		err = report.WriteJSON(os.Stdout)
+	default:
+		err = fmt.Errorf("unknown format %q", *format)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if report.HasDrift() {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,68 @@
+// command untuk menjalankan retention user_logs dan mengembalikan file archive ke database
+//
+// contoh :
+//
+//	go run ./cmd/userlog-archive -days 90 -dir ./archive archive
+//	go run ./cmd/userlog-archive restore ./archive/user_logs_1700000000000_1700000500000_1.ndjson.gz
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func main() {
+	dsn := flag.String("dsn", "root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local", "mysql dsn")
+	days := flag.Int("days", 90, "archive user logs older than this many days")
+	dir := flag.String("dir", "archive", "archive directory")
+	batch := flag.Int("batch", 1000, "rows per archive file")
+	partitioned := flag.Bool("partitioned", false, "manage monthly partitions for user_logs (mysql)")
+	flag.Parse()
+
+	db, err := gorm.Open(mysql.Open(*dsn), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+
+	switch flag.Arg(0) {
+	case "archive":
+		retention := belajar_go_lang_gorm.NewUserLogRetention(db, belajar_go_lang_gorm.RetentionPolicy{
+			Days:        *days,
+			ArchiveDir:  *dir,
+			BatchSize:   *batch,
+			Partitioned: *partitioned,
+		})
+
+		result, err := retention.Run(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("archived %d rows into %d files, dropped partitions %v\n", result.Archived, len(result.Files), result.DroppedPartitions)
+
+	case "restore":
+		for _, path := range flag.Args()[1:] {
+			restored, err := belajar_go_lang_gorm.RestoreUserLogArchive(ctx, db, path)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+
+			fmt.Printf("restored %d rows from %s\n", restored, path)
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, "usage: userlog-archive [flags] archive | restore <file>...")
+		os.Exit(2)
+	}
+}
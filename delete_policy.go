@@ -0,0 +1,527 @@
+package belajar_go_lang_gorm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi delete policy per relasi
+// policy di deklarasikan pada tag gorm field relasi (has one, has many, many to many), contoh :
+//
+//	Wallet    Wallet    `gorm:"foreignKey:user_id;references:id;deletePolicy:restrict"`
+//	Addresses []Address `gorm:"foreignKey:user_id;references:id;deletePolicy:cascade"`
+//
+// policy dijalankan oleh plugin DeletePolicyEnforcer sebelum data parent di hapus (untuk dialect tanpa foreign key),-
+// dan dibuat sebagai foreign key constraint di database menggunakan MigrateDeletePolicies
+type DeletePolicy string
+
+const (
+	DeleteCascade     DeletePolicy = "cascade"      // data child ikut di hapus permanen (unscoped)
+	DeleteRestrict    DeletePolicy = "restrict"     // parent tidak boleh di hapus selama masih ada data child
+	DeleteSetNull     DeletePolicy = "set_null"     // foreign key pada child di isi NULL
+	DeleteSoftCascade DeletePolicy = "soft_cascade" // data child di soft delete (child wajib memiliki gorm.DeletedAt)
+)
+
+const deletePolicyTag = "DELETEPOLICY"
+
+var ErrDeleteRestricted = errors.New("delete restricted")
+
+// error ketika parent tidak bisa di hapus karena relasi dengan policy restrict masih memiliki data
+type RestrictedDeleteError struct {
+	Table    string
+	Blocking []BlockingRelation
+}
+
+// relasi yang menghalangi delete beserta jumlah data nya
+type BlockingRelation struct {
+	Relation string
+	Table    string
+	Count    int64
+}
+
+func (e *RestrictedDeleteError) Error() string {
+	relations := make([]string, len(e.Blocking))
+	for i, blocking := range e.Blocking {
+		relations[i] = fmt.Sprintf("%s (%d rows in %s)", blocking.Relation, blocking.Count, blocking.Table)
+	}
+
+	return fmt.Sprintf("cannot delete %s: restricted by %s", e.Table, strings.Join(relations, ", "))
+}
+
+// agar errors.Is(err, ErrDeleteRestricted) bisa digunakan
+func (e *RestrictedDeleteError) Is(target error) bool {
+	return target == ErrDeleteRestricted
+}
+
+// policy satu relasi beserta kolom yang menghubungkan parent dan child
+type relationPolicy struct {
+	relationship *schema.Relationship
+	policy       DeletePolicy
+	table        string // tabel child, atau tabel penghubung untuk many to many
+	parentColumn string // kolom pada parent, biasanya primary key
+	childColumn  string // kolom foreign key pada child / tabel penghubung
+	conditions   map[string]interface{}
+}
+
+// membaca delete policy dari relasi pada schema
+func deletePolicies(s *schema.Schema) ([]relationPolicy, error) {
+	names := make([]string, 0, len(s.Relationships.Relations))
+	for name := range s.Relationships.Relations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var policies []relationPolicy
+	for _, name := range names {
+		relationship := s.Relationships.Relations[name]
+		if relationship.Field == nil || relationship.Field.Schema != s {
+			continue
+		}
+
+		policy := DeletePolicy(strings.ToLower(relationship.Field.TagSettings[deletePolicyTag]))
+		if policy == "" {
+			continue
+		}
+
+		switch relationship.Type {
+		case schema.HasOne, schema.HasMany, schema.Many2Many:
+		default:
+			return nil, fmt.Errorf("delete policy: %s.%s: policy only applies to has one, has many and many to many", s.Name, name)
+		}
+
+		switch policy {
+		case DeleteCascade, DeleteRestrict:
+		case DeleteSetNull:
+			if relationship.Type == schema.Many2Many {
+				return nil, fmt.Errorf("delete policy: %s.%s: set_null can not be used on many to many", s.Name, name)
+			}
+		case DeleteSoftCascade:
+			if relationship.Type != schema.Many2Many && softDeleteField(relationship.FieldSchema) == nil {
+				return nil, fmt.Errorf("delete policy: %s.%s: soft_cascade requires gorm.DeletedAt on %s", s.Name, name, relationship.FieldSchema.Name)
+			}
+		default:
+			return nil, fmt.Errorf("delete policy: %s.%s: unknown policy %q", s.Name, name, policy)
+		}
+
+		rp := relationPolicy{relationship: relationship, policy: policy, table: relationship.FieldSchema.Table, conditions: map[string]interface{}{}}
+		if relationship.JoinTable != nil {
+			rp.table = relationship.JoinTable.Table
+		}
+
+		for _, reference := range relationship.References {
+			switch {
+			case reference.PrimaryKey == nil:
+				// polymorphic, contoh owner_type = 'users'
+				rp.conditions[reference.ForeignKey.DBName] = reference.PrimaryValue
+			case reference.OwnPrimaryKey && rp.childColumn == "":
+				rp.parentColumn = reference.PrimaryKey.DBName
+				rp.childColumn = reference.ForeignKey.DBName
+			}
+		}
+		if rp.childColumn == "" {
+			continue
+		}
+
+		policies = append(policies, rp)
+	}
+
+	return policies, nil
+}
+
+func softDeleteField(s *schema.Schema) *schema.Field {
+	for _, field := range s.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return field
+		}
+	}
+
+	return nil
+}
+
+// plugin untuk menjalankan delete policy di aplikasi
+// contoh : db.Use(NewDeletePolicyEnforcer())
+//
+// karena policy dijalankan dengan beberapa query, gunakan transaction jika SkipDefaultTransaction aktif-
+// agar data parent dan child tetap konsisten ketika terjadi error
+// policy yang tabel child nya belum ada (contoh tabel penghubung many to many yang belum di migrasi) dilewati
+type DeletePolicyEnforcer struct {
+	tables sync.Map // tabel child yang sudah pasti ada, agar HasTable tidak dijalankan setiap delete
+}
+
+func NewDeletePolicyEnforcer() *DeletePolicyEnforcer {
+	return &DeletePolicyEnforcer{}
+}
+
+func (e *DeletePolicyEnforcer) Name() string {
+	return "delete_policy"
+}
+
+func (e *DeletePolicyEnforcer) Initialize(db *gorm.DB) error {
+	// setelah relasi yang di pilih dengan Select() di hapus, dan sebelum parent di hapus
+	return db.Callback().Delete().
+		After("gorm:delete_before_associations").
+		Before("gorm:delete").
+		Register("delete_policy:enforce", e.enforce)
+}
+
+func (e *DeletePolicyEnforcer) enforce(db *gorm.DB) {
+	if db.Error != nil || db.DryRun || db.Statement.Schema == nil {
+		return
+	}
+
+	policies, err := deletePolicies(db.Statement.Schema)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+
+	policies = e.existingTables(db, policies)
+	if len(policies) == 0 {
+		return
+	}
+
+	keys := map[string][]interface{}{}
+	for _, policy := range policies {
+		if _, ok := keys[policy.parentColumn]; ok {
+			continue
+		}
+
+		values, ok, err := deletedKeys(db, policy.parentColumn)
+		if err != nil {
+			db.AddError(err)
+			return
+		}
+		if !ok {
+			return
+		}
+		keys[policy.parentColumn] = values
+	}
+
+	err = applyPolicies(db, policies, keys)
+	if err != nil {
+		db.AddError(err)
+	}
+}
+
+// policy yang tabel child nya ada di database, tabel yang belum ada tidak di simpan ke cache karena bisa dibuat kemudian
+func (e *DeletePolicyEnforcer) existingTables(db *gorm.DB, policies []relationPolicy) []relationPolicy {
+	existing := make([]relationPolicy, 0, len(policies))
+	for _, policy := range policies {
+		if _, ok := e.tables.Load(policy.table); !ok {
+			if !db.Session(&gorm.Session{NewDB: true}).Migrator().HasTable(policy.table) {
+				continue
+			}
+			e.tables.Store(policy.table, true)
+		}
+
+		existing = append(existing, policy)
+	}
+
+	return existing
+}
+
+// mengambil nilai kolom parent dari data yang akan di hapus, menggunakan kondisi yang sama dengan query delete
+// ok bernilai false jika delete tidak memiliki kondisi (gorm akan mengembalikan ErrMissingWhereClause)
+func deletedKeys(db *gorm.DB, column string) ([]interface{}, bool, error) {
+	stmt := db.Statement
+	query := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	conditions := false
+
+	if where, ok := stmt.Clauses["WHERE"]; ok {
+		if expression, ok := where.Expression.(clause.Where); ok && len(expression.Exprs) > 0 {
+			query = query.Clauses(expression)
+			conditions = true
+		}
+	}
+
+	// primary key dari data yang di berikan, contoh db.Delete(&user)
+	if stmt.ReflectValue.IsValid() && len(stmt.Schema.PrimaryFields) > 0 {
+		_, values := schema.GetIdentityFieldValuesMap(stmt.Context, stmt.ReflectValue, stmt.Schema.PrimaryFields)
+		if len(values) > 0 {
+			columns := make([]clause.Column, len(stmt.Schema.PrimaryFields))
+			for i, field := range stmt.Schema.PrimaryFields {
+				columns[i] = clause.Column{Name: field.DBName}
+			}
+
+			var column interface{} = columns
+			if len(columns) == 1 {
+				column = columns[0]
+			}
+			query = query.Where(clause.IN{Column: column, Values: identityValues(values)})
+			conditions = true
+		}
+	}
+
+	if !conditions && !stmt.AllowGlobalUpdate {
+		return nil, false, nil
+	}
+
+	// parent yang sudah di soft delete tidak perlu di proses lagi
+	if field := softDeleteField(stmt.Schema); field != nil && !stmt.Unscoped {
+		query = query.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: nil})
+	}
+
+	var keys []interface{}
+	err := query.Distinct().Pluck(column, &keys).Error
+
+	return keys, true, err
+}
+
+func identityValues(values [][]interface{}) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		if len(value) == 1 {
+			result[i] = value[0]
+		} else {
+			result[i] = value
+		}
+	}
+
+	return result
+}
+
+func applyPolicies(db *gorm.DB, policies []relationPolicy, keys map[string][]interface{}) error {
+	stmt := db.Statement
+	tx := db.Session(&gorm.Session{NewDB: true})
+
+	// restrict di cek lebih dulu, sehingga tidak ada data child yang berubah jika delete dibatalkan
+	var blocking []BlockingRelation
+	for _, policy := range policies {
+		if policy.policy != DeleteRestrict || len(keys[policy.parentColumn]) == 0 {
+			continue
+		}
+
+		var count int64
+		err := policy.children(tx, keys[policy.parentColumn]).Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			blocking = append(blocking, BlockingRelation{Relation: policy.relationship.Name, Table: policy.table, Count: count})
+		}
+	}
+	if len(blocking) > 0 {
+		return &RestrictedDeleteError{Table: stmt.Table, Blocking: blocking}
+	}
+
+	// jika parent hanya di soft delete, cascade juga menggunakan soft delete agar data bisa di kembalikan
+	parentSoftDelete := softDeleteField(stmt.Schema) != nil && !stmt.Unscoped
+
+	for _, policy := range policies {
+		if len(keys[policy.parentColumn]) == 0 {
+			continue
+		}
+
+		var err error
+		switch policy.policy {
+		case DeleteCascade, DeleteSoftCascade:
+			children := policy.children(tx, keys[policy.parentColumn])
+			if policy.policy == DeleteCascade && !parentSoftDelete {
+				children = children.Unscoped()
+			}
+			err = children.Delete(policy.model()).Error
+		case DeleteSetNull:
+			err = policy.children(tx, keys[policy.parentColumn]).UpdateColumn(policy.childColumn, nil).Error
+		}
+		if err != nil {
+			return fmt.Errorf("delete policy %s on %s.%s: %w", policy.policy, stmt.Schema.Name, policy.relationship.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// model child, atau model tabel penghubung untuk many to many
+func (p relationPolicy) model() interface{} {
+	if p.relationship.JoinTable != nil {
+		return reflect.New(p.relationship.JoinTable.ModelType).Interface()
+	}
+
+	return reflect.New(p.relationship.FieldSchema.ModelType).Interface()
+}
+
+// query data child milik parent dengan keys
+func (p relationPolicy) children(tx *gorm.DB, keys []interface{}) *gorm.DB {
+	query := tx.Model(p.model()).Where(clause.IN{Column: clause.Column{Name: p.childColumn}, Values: keys})
+	if p.relationship.JoinTable != nil {
+		query = query.Table(p.table)
+	}
+
+	for column, value := range p.conditions {
+		query = query.Where(clause.Eq{Column: clause.Column{Name: column}, Value: value})
+	}
+
+	return query
+}
+
+// foreign key constraint untuk delete policy
+// nama constraint sama dengan nama constraint bawaan gorm untuk relasi tersebut (contoh fk_users_wallet),-
+// sehingga constraint dari AutoMigrate (tanpa ON DELETE) di ganti, bukan ditambahkan constraint kedua
+type DeleteConstraint struct {
+	Name             string
+	Table            string // tabel child atau tabel penghubung
+	Column           string
+	ReferencedTable  string
+	ReferencedColumn string
+	OnDelete         string // CASCADE, RESTRICT atau SET NULL
+}
+
+// daftar foreign key constraint dari delete policy pada model
+// soft_cascade tidak dibuat sebagai constraint, karena data child tetap ada ketika parent di hapus
+func DeleteConstraints(db *gorm.DB, models ...interface{}) ([]DeleteConstraint, error) {
+	var constraints []DeleteConstraint
+
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		err := stmt.Parse(model)
+		if err != nil {
+			return nil, err
+		}
+
+		policies, err := deletePolicies(stmt.Schema)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, policy := range policies {
+			onDelete := map[DeletePolicy]string{
+				DeleteCascade:  "CASCADE",
+				DeleteRestrict: "RESTRICT",
+				DeleteSetNull:  "SET NULL",
+			}[policy.policy]
+			if onDelete == "" {
+				continue
+			}
+
+			constraints = append(constraints, DeleteConstraint{
+				Name:             policy.constraintName(stmt.Schema),
+				Table:            policy.table,
+				Column:           policy.childColumn,
+				ReferencedTable:  stmt.Schema.Table,
+				ReferencedColumn: policy.parentColumn,
+				OnDelete:         onDelete,
+			})
+		}
+	}
+
+	return constraints, nil
+}
+
+// nama constraint bawaan gorm untuk relasi, untuk many to many di ambil dari relasi tabel penghubung ke parent
+// relasi dengan tag constraint:- tidak memiliki constraint bawaan, sehingga menggunakan nama fk_<tabel>_<kolom>_<parent>
+func (p relationPolicy) constraintName(parent *schema.Schema) string {
+	relationship := p.relationship
+	if relationship.JoinTable != nil {
+		relationship = nil
+		for _, joinRelationship := range p.relationship.JoinTable.Relationships.Relations {
+			if joinRelationship.FieldSchema == parent {
+				relationship = joinRelationship
+				break
+			}
+		}
+	}
+
+	if relationship != nil {
+		if constraint := relationship.ParseConstraint(); constraint != nil {
+			return constraint.Name
+		}
+	}
+
+	return legacyConstraintName(p.table, p.childColumn, parent.Table)
+}
+
+// nama constraint delete policy sebelum menggunakan nama constraint bawaan gorm
+func legacyConstraintName(table string, column string, parent string) string {
+	return fmt.Sprintf("fk_%s_%s_%s", table, column, parent)
+}
+
+// membuat foreign key constraint dari delete policy
+// constraint dengan nama yang sama (constraint bawaan gorm dari AutoMigrate) di hapus dan dibuat ulang jika ON DELETE nya berbeda,-
+// constraint yang sudah sesuai tidak dibuat ulang, dan constraint lama dengan nama fk_<tabel>_<kolom>_<parent> di hapus
+// tabel child yang belum ada (contoh tabel penghubung many to many yang belum di migrasi) dilewati
+// sqlite tidak mendukung penambahan constraint pada tabel yang sudah ada, sehingga policy hanya dijalankan oleh DeletePolicyEnforcer
+// data child yang tidak memiliki parent (orphan) harus di bersihkan terlebih dahulu, jika tidak pembuatan constraint akan gagal
+func MigrateDeletePolicies(db *gorm.DB, models ...interface{}) error {
+	if db.Dialector.Name() == "sqlite" {
+		return nil
+	}
+
+	constraints, err := DeleteConstraints(db, models...)
+	if err != nil {
+		return err
+	}
+
+	migrator := db.Migrator()
+	for _, constraint := range constraints {
+		if !migrator.HasTable(constraint.Table) {
+			continue
+		}
+
+		legacy := legacyConstraintName(constraint.Table, constraint.Column, constraint.ReferencedTable)
+		if legacy != constraint.Name && migrator.HasConstraint(constraint.Table, legacy) {
+			err = dropForeignKey(db, constraint.Table, legacy)
+			if err != nil {
+				return fmt.Errorf("delete policy: constraint %s: %w", legacy, err)
+			}
+		}
+
+		if migrator.HasConstraint(constraint.Table, constraint.Name) {
+			rule, err := deleteRule(db, constraint)
+			if err != nil {
+				return fmt.Errorf("delete policy: constraint %s: %w", constraint.Name, err)
+			}
+			if rule == constraint.OnDelete {
+				continue
+			}
+
+			err = dropForeignKey(db, constraint.Table, constraint.Name)
+			if err != nil {
+				return fmt.Errorf("delete policy: constraint %s: %w", constraint.Name, err)
+			}
+		}
+
+		err = db.Exec("ALTER TABLE ? ADD CONSTRAINT ? FOREIGN KEY (?) REFERENCES ?(?) ON DELETE "+constraint.OnDelete,
+			clause.Table{Name: constraint.Table},
+			clause.Column{Name: constraint.Name},
+			clause.Column{Name: constraint.Column},
+			clause.Table{Name: constraint.ReferencedTable},
+			clause.Column{Name: constraint.ReferencedColumn},
+		).Error
+		if err != nil {
+			return fmt.Errorf("delete policy: constraint %s: %w", constraint.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// aturan ON DELETE dari foreign key yang sudah ada (contoh CASCADE, RESTRICT, NO ACTION)
+// mengembalikan string kosong jika tidak ditemukan, sehingga constraint akan dibuat ulang
+func deleteRule(db *gorm.DB, constraint DeleteConstraint) (string, error) {
+	var rules []string
+	err := db.Raw("SELECT delete_rule FROM information_schema.referential_constraints WHERE constraint_schema = ? AND constraint_name = ?",
+		db.Migrator().CurrentDatabase(), constraint.Name).Scan(&rules).Error
+	if err != nil || len(rules) == 0 {
+		return "", err
+	}
+
+	return strings.ToUpper(rules[0]), nil
+}
+
+// menghapus foreign key, mysql menggunakan DROP FOREIGN KEY (DROP CONSTRAINT baru tersedia di mysql 8.0.19)
+func dropForeignKey(db *gorm.DB, table string, name string) error {
+	sql := "ALTER TABLE ? DROP CONSTRAINT ?"
+	if db.Dialector.Name() == "mysql" {
+		sql = "ALTER TABLE ? DROP FOREIGN KEY ?"
+	}
+
+	return db.Exec(sql, clause.Table{Name: table}, clause.Column{Name: name}).Error
+}
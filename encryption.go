@@ -0,0 +1,406 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi enkripsi per kolom menggunakan serializer
+// contoh tag : `gorm:"column:address;serializer:encrypted"`
+// value di enkripsi dengan AES-GCM sebelum di simpan, dan di dekripsi ketika di baca dari database
+// format value di database : enc:<key id>:<base64(nonce + ciphertext)>
+//
+// tambahkan tag 'deterministic' agar plaintext yang sama selalu menghasilkan ciphertext yang sama,-
+// sehingga kolom tersebut bisa dicari dengan where (lihat EncryptedEquals)
+// contoh tag : `gorm:"column:email;serializer:encrypted;deterministic"`
+const encryptedPrefix = "enc:"
+
+var ErrKeyringNotConfigured = errors.New("encryption keyring is not configured")
+
+// keyring berisi daftar key AES-256 (32 byte) beserta id nya
+// data baru selalu di enkripsi dengan active key, key lama tetap disimpan agar data lama masih bisa di dekripsi
+type Keyring struct {
+	active string
+	keys   map[string][]byte
+
+	// key terpisah untuk membuat nonce deterministic, diturunkan dari setiap key dengan HKDF
+	// sehingga key AES tidak dipakai untuk dua keperluan (enkripsi dan HMAC)
+	nonceKeys map[string][]byte
+
+	// key untuk hash subject pada erasure audit (lihat ErasureSubjectHash), diturunkan dengan label yang berbeda
+	subjectKeys map[string][]byte
+}
+
+// label HKDF untuk key turunan, setiap keperluan harus memiliki label yang berbeda
+const (
+	nonceKeyInfo   = "belajar-go-lang-gorm/encrypted/deterministic-nonce"
+	subjectKeyInfo = "belajar-go-lang-gorm/gdpr/erasure-subject"
+)
+
+func NewKeyring(active string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("active key %q not found in keyring", active)
+	}
+
+	copied := make(map[string][]byte, len(keys))
+	nonceKeys := make(map[string][]byte, len(keys))
+	subjectKeys := make(map[string][]byte, len(keys))
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		copied[id] = append([]byte(nil), key...)
+
+		nonceKey, err := hkdf.Key(sha256.New, key, nil, nonceKeyInfo, 32)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		nonceKeys[id] = nonceKey
+
+		subjectKey, err := hkdf.Key(sha256.New, key, nil, subjectKeyInfo, 32)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		subjectKeys[id] = subjectKey
+	}
+
+	return &Keyring{active: active, keys: copied, nonceKeys: nonceKeys, subjectKeys: subjectKeys}, nil
+}
+
+// membaca keyring dari file json lokal, key ditulis dalam base64
+// contoh isi file : {"active": "2025-02", "keys": {"2025-01": "base64...", "2025-02": "base64..."}}
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Active string            `json:"active"`
+		Keys   map[string]string `json:"keys"`
+	}
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+
+	return NewKeyring(file.Active, keys)
+}
+
+// id key yang digunakan untuk enkripsi data baru
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// enkripsi plaintext dengan active key
+func (k *Keyring) Encrypt(plaintext []byte, deterministic bool) (string, error) {
+	return k.encryptWith(k.active, plaintext, deterministic)
+}
+
+func (k *Keyring) encryptWith(id string, plaintext []byte, deterministic bool) (string, error) {
+	key := k.keys[id]
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		// nonce diturunkan dari plaintext (HMAC dengan nonce key), sehingga plaintext yang sama menghasilkan ciphertext yang sama
+		mac := hmac.New(sha256.New, k.nonceKeys[id])
+		mac.Write([]byte("nonce"))
+		mac.Write(plaintext)
+		copy(nonce, mac.Sum(nil))
+	} else {
+		_, err = rand.Read(nonce)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	sealed := aead.Seal(nonce, nonce, plaintext, []byte(id))
+	return encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// dekripsi value, value yang tidak memiliki prefix enc: dianggap plaintext (data lama sebelum di enkripsi)
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	id, ok := EncryptedKeyID(value)
+	if !ok {
+		return []byte(value), nil
+	}
+
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown encryption key %q", id)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encryptedPrefix)+len(id)+1:])
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("encrypted value is too short")
+	}
+
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(id))
+}
+
+// mengambil id key dari value yang sudah di enkripsi
+func EncryptedKeyID(value string) (string, bool) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return "", false
+	}
+
+	id, _, ok := strings.Cut(value[len(encryptedPrefix):], ":")
+	return id, ok
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// keyring yang digunakan oleh serializer encrypted
+var encryptionKeyring atomic.Pointer[Keyring]
+
+// mengatur keyring yang digunakan serializer, dipanggil sekali ketika aplikasi start (dan ketika key di rotasi)
+func SetEncryptionKeyring(keyring *Keyring) {
+	encryptionKeyring.Store(keyring)
+}
+
+func currentKeyring() (*Keyring, error) {
+	keyring := encryptionKeyring.Load()
+	if keyring == nil {
+		return nil, ErrKeyringNotConfigured
+	}
+
+	return keyring, nil
+}
+
+func init() {
+	// serializer harus terdaftar sebelum schema model di parse
+	schema.RegisterSerializer("encrypted", EncryptedSerializer{})
+}
+
+// serializer untuk tag serializer:encrypted, mendukung field string dan []byte
+type EncryptedSerializer struct{}
+
+// membaca value dari database dan dekripsi
+func (EncryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch dbValue := dbValue.(type) {
+	case nil:
+		return field.Set(ctx, dst, reflect.Zero(field.FieldType).Interface())
+	case string:
+		value = dbValue
+	case []byte:
+		value = string(dbValue)
+	default:
+		return fmt.Errorf("unsupported encrypted value %T for field %s", dbValue, field.Name)
+	}
+
+	keyring, err := currentKeyring()
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := keyring.Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("decrypt field %s: %w", field.Name, err)
+	}
+
+	if field.FieldType.Kind() == reflect.String {
+		return field.Set(ctx, dst, string(plaintext))
+	}
+
+	return field.Set(ctx, dst, plaintext)
+}
+
+// enkripsi value sebelum di simpan ke database
+func (EncryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	switch fieldValue := fieldValue.(type) {
+	case string:
+		plaintext = []byte(fieldValue)
+	case []byte:
+		if fieldValue == nil {
+			return nil, nil
+		}
+		plaintext = fieldValue
+	default:
+		return nil, fmt.Errorf("unsupported encrypted field type %T for field %s", fieldValue, field.Name)
+	}
+
+	keyring, err := currentKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	_, deterministic := field.TagSettings["DETERMINISTIC"]
+	return keyring.Encrypt(plaintext, deterministic)
+}
+
+// implementasi scopes, mencari data berdasarkan kolom encrypted deterministic
+// ciphertext dibuat untuk setiap key pada keyring, sehingga data yang belum di rotasi tetap ditemukan
+func EncryptedEquals(column string, plaintext string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		keyring, err := currentKeyring()
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+
+		values := make([]interface{}, 0, len(keyring.keys))
+		for id := range keyring.keys {
+			value, err := keyring.encryptWith(id, []byte(plaintext), true)
+			if err != nil {
+				db.AddError(err)
+				return db
+			}
+			values = append(values, value)
+		}
+
+		return db.Where(clause.IN{Column: clause.Column{Name: column}, Values: values})
+	}
+}
+
+// job untuk rotasi key, data yang masih di enkripsi dengan key lama (atau masih plaintext)-
+// di enkripsi ulang dengan active key secara bertahap per batch
+type KeyRotationJob struct {
+	db        *gorm.DB
+	models    []interface{}
+	batchSize int
+	interval  time.Duration
+}
+
+func NewKeyRotationJob(db *gorm.DB, batchSize int, interval time.Duration, models ...interface{}) *KeyRotationJob {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	return &KeyRotationJob{db: db, models: models, batchSize: batchSize, interval: interval}
+}
+
+// menjalankan rotasi secara berkala sampai context dibatalkan
+func (j *KeyRotationJob) Run(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		_, err := j.RotateOnce(ctx)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// enkripsi ulang semua data pada setiap model yang belum menggunakan active key
+// mengembalikan jumlah baris yang di enkripsi ulang
+func (j *KeyRotationJob) RotateOnce(ctx context.Context) (int, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, model := range j.models {
+		rotated, err := j.rotateModel(ctx, keyring, model)
+		total += rotated
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
+
+func (j *KeyRotationJob) rotateModel(ctx context.Context, keyring *Keyring, model interface{}) (int, error) {
+	s, err := schema.Parse(model, &sync.Map{}, j.db.NamingStrategy)
+	if err != nil {
+		return 0, err
+	}
+
+	var columns []string
+	var conditions []string
+	var args []interface{}
+	activePrefix := encryptedPrefix + keyring.ActiveKeyID() + ":%"
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.TagSettings["SERIALIZER"] != "encrypted" {
+			continue
+		}
+
+		columns = append(columns, field.DBName)
+		conditions = append(conditions, "("+field.DBName+" IS NOT NULL AND "+field.DBName+" NOT LIKE ?)")
+		args = append(args, activePrefix)
+	}
+
+	if len(columns) == 0 {
+		return 0, nil
+	}
+
+	rotated := 0
+	batch := reflect.New(reflect.SliceOf(s.ModelType))
+	result := j.db.WithContext(ctx).Model(model).Where(strings.Join(conditions, " OR "), args...).
+		FindInBatches(batch.Interface(), j.batchSize, func(tx *gorm.DB, number int) error {
+			rows := batch.Elem()
+			for i := 0; i < rows.Len(); i++ {
+				// value di baca (dekripsi) lalu di simpan ulang, serializer akan mengenkripsi dengan active key
+				// UpdateColumns tidak menjalankan hook dan tidak mengubah updated_at
+				err := j.db.WithContext(ctx).Model(rows.Index(i).Addr().Interface()).
+					Select(columns).UpdateColumns(rows.Index(i).Addr().Interface()).Error
+				if err != nil {
+					return err
+				}
+				rotated++
+			}
+			return nil
+		})
+
+	return rotated, result.Error
+}
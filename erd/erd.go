@@ -0,0 +1,383 @@
+// package erd membuat ER diagram (Mermaid atau Graphviz) dan data dictionary (Markdown) dari schema model gorm
+//
+// relasi dibaca dari schema gorm (has one, has many, belongs to, many to many beserta tabel penghubung nya),-
+// sehingga dokumentasi selalu di generate dari kode dan tidak perlu ditulis manual
+//
+// contoh :
+//
+//	diagram, err := erd.Parse(schema.NamingStrategy{}, models...)
+//	err = diagram.WriteMermaid(os.Stdout)
+package erd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// jenis relasi
+const (
+	HasOne    = "has_one"
+	HasMany   = "has_many"
+	BelongsTo = "belongs_to"
+	Many2Many = "many2many"
+)
+
+type Column struct {
+	Name       string
+	Type       string
+	PrimaryKey bool
+	ForeignKey bool
+	Unique     bool
+	Nullable   bool
+	Default    string
+	Comment    string
+}
+
+type Table struct {
+	Name      string
+	Model     string // nama struct, kosong untuk tabel penghubung many to many
+	JoinTable bool
+	Columns   []Column
+}
+
+// relasi antar tabel, From adalah tabel induk (yang di referensikan)
+// untuk many to many, JoinTable berisi tabel penghubung, ForeignKeys kolom yang mengarah ke From,-
+// dan JoinReferences kolom yang mengarah ke To
+type Relation struct {
+	From           string
+	To             string
+	Type           string
+	Field          string // nama field relasi pada model
+	ForeignKeys    []string
+	JoinTable      string
+	JoinReferences []string
+}
+
+type Diagram struct {
+	Tables    []Table
+	Relations []Relation
+}
+
+// membaca schema semua model
+func Parse(namer schema.Namer, models ...interface{}) (*Diagram, error) {
+	cache := &sync.Map{}
+	schemas := make([]*schema.Schema, 0, len(models))
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, namer)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, s)
+	}
+
+	foreignKeys := map[string]bool{}
+	tables := map[string]*schema.Schema{}
+	joinTables := map[string]*schema.Schema{}
+	relations := map[string]Relation{}
+
+	for _, s := range schemas {
+		tables[s.Table] = s
+
+		for _, relationship := range s.Relationships.Relations {
+			// Relations juga bisa berisi relasi milik schema lain (relasi bersarang),-
+			// sehingga tabel pemilik relasi diambil dari relationship.Schema
+			owner := relationship.Schema.Table
+			relation := Relation{Field: relationship.Name}
+			for _, reference := range relationship.References {
+				if reference.ForeignKey == nil {
+					continue
+				}
+				foreignKeys[reference.ForeignKey.Schema.Table+"."+reference.ForeignKey.DBName] = true
+			}
+
+			switch relationship.Type {
+			case schema.HasOne, schema.HasMany:
+				relation.From = owner
+				relation.To = relationship.FieldSchema.Table
+				relation.Type = HasOne
+				if relationship.Type == schema.HasMany {
+					relation.Type = HasMany
+				}
+				relation.ForeignKeys = referenceColumns(relationship, true)
+			case schema.BelongsTo:
+				relation.From = relationship.FieldSchema.Table
+				relation.To = owner
+				relation.Type = BelongsTo
+				relation.ForeignKeys = referenceColumns(relationship, true)
+			case schema.Many2Many:
+				relation.From = owner
+				relation.To = relationship.FieldSchema.Table
+				relation.Type = Many2Many
+				relation.JoinTable = relationship.JoinTable.Table
+				relation.ForeignKeys = referenceColumns(relationship, true)
+				relation.JoinReferences = referenceColumns(relationship, false)
+				joinTables[relationship.JoinTable.Table] = relationship.JoinTable
+			default:
+				continue
+			}
+
+			key := relationKey(relation)
+			// belongs to yang sama dengan has one / has many dari sisi lain cukup di tulis sekali
+			if existing, ok := relations[key]; ok && existing.Type != BelongsTo {
+				continue
+			}
+			relations[key] = relation
+		}
+	}
+
+	diagram := &Diagram{}
+	for name, s := range tables {
+		diagram.Tables = append(diagram.Tables, newTable(name, s, false, foreignKeys))
+	}
+	for name, s := range joinTables {
+		if _, ok := tables[name]; !ok {
+			diagram.Tables = append(diagram.Tables, newTable(name, s, true, foreignKeys))
+		}
+	}
+	sort.Slice(diagram.Tables, func(i, j int) bool {
+		return diagram.Tables[i].Name < diagram.Tables[j].Name
+	})
+
+	for _, relation := range relations {
+		diagram.Relations = append(diagram.Relations, relation)
+	}
+	sort.Slice(diagram.Relations, func(i, j int) bool {
+		return relationKey(diagram.Relations[i]) < relationKey(diagram.Relations[j])
+	})
+
+	return diagram, nil
+}
+
+// kunci relasi, many to many dari kedua sisi (User.LikeProducts dan Product.LikedByUsers) dianggap sama
+func relationKey(relation Relation) string {
+	if relation.Type == Many2Many {
+		sides := []string{relation.From + ":" + strings.Join(relation.ForeignKeys, ","), relation.To + ":" + strings.Join(relation.JoinReferences, ",")}
+		sort.Strings(sides)
+		return relation.JoinTable + "|" + strings.Join(sides, "|")
+	}
+
+	return relation.From + "|" + relation.To + "|" + strings.Join(relation.ForeignKeys, ",")
+}
+
+// kolom foreign key pada relasi, own = true untuk referensi ke tabel pemilik relasi (untuk many to many)
+func referenceColumns(relationship *schema.Relationship, own bool) []string {
+	var columns []string
+	for _, reference := range relationship.References {
+		if reference.ForeignKey == nil {
+			continue
+		}
+		if relationship.Type == schema.Many2Many && reference.OwnPrimaryKey != own {
+			continue
+		}
+		columns = append(columns, reference.ForeignKey.DBName)
+	}
+
+	return columns
+}
+
+func newTable(name string, s *schema.Schema, joinTable bool, foreignKeys map[string]bool) Table {
+	table := Table{Name: name, JoinTable: joinTable}
+	if !joinTable {
+		table.Model = s.Name
+	}
+
+	for _, field := range s.Fields {
+		if field.DBName == "" {
+			continue
+		}
+
+		dataType := string(field.DataType)
+		if field.Size > 0 && field.TagSettings["SIZE"] != "" {
+			dataType = fmt.Sprintf("%s(%d)", dataType, field.Size)
+		}
+
+		_, unique := field.TagSettings["UNIQUEINDEX"]
+		table.Columns = append(table.Columns, Column{
+			Name:       field.DBName,
+			Type:       dataType,
+			PrimaryKey: field.PrimaryKey,
+			ForeignKey: foreignKeys[name+"."+field.DBName],
+			Unique:     unique || field.Unique,
+			Nullable:   !field.NotNull && !field.PrimaryKey,
+			Default:    field.DefaultValue,
+			Comment:    field.Comment,
+		})
+	}
+
+	return table
+}
+
+var identifierPattern = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ER diagram dalam format Mermaid (erDiagram)
+func (d *Diagram) WriteMermaid(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("erDiagram\n")
+
+	for _, relation := range d.Relations {
+		label := strings.Join(relation.ForeignKeys, ", ")
+		switch relation.Type {
+		case HasOne:
+			fmt.Fprintf(&builder, "    %s ||--o| %s : %q\n", relation.From, relation.To, label)
+		case HasMany, BelongsTo:
+			fmt.Fprintf(&builder, "    %s ||--o{ %s : %q\n", relation.From, relation.To, label)
+		case Many2Many:
+			fmt.Fprintf(&builder, "    %s ||--o{ %s : %q\n", relation.From, relation.JoinTable, label)
+			fmt.Fprintf(&builder, "    %s ||--o{ %s : %q\n", relation.To, relation.JoinTable, strings.Join(relation.JoinReferences, ", "))
+		}
+	}
+
+	for _, table := range d.Tables {
+		fmt.Fprintf(&builder, "    %s {\n", table.Name)
+		for _, column := range table.Columns {
+			// mermaid hanya menerima satu kata untuk tipe data, ukuran kolom tidak di tulis
+			dataType, _, _ := strings.Cut(column.Type, "(")
+			fmt.Fprintf(&builder, "        %s %s", identifierPattern.ReplaceAllString(dataType, "_"), column.Name)
+			if keys := columnKeys(column); len(keys) > 0 {
+				builder.WriteString(" " + strings.Join(keys, ", "))
+			}
+			builder.WriteString("\n")
+		}
+		builder.WriteString("    }\n")
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// ER diagram dalam format Graphviz (dot)
+func (d *Diagram) WriteGraphviz(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("digraph erd {\n")
+	builder.WriteString("    rankdir=LR;\n")
+	builder.WriteString("    node [shape=record, fontname=\"Helvetica\"];\n")
+
+	for _, table := range d.Tables {
+		rows := make([]string, 0, len(table.Columns))
+		for _, column := range table.Columns {
+			row := column.Name + " : " + column.Type
+			if keys := columnKeys(column); len(keys) > 0 {
+				row += " (" + strings.Join(keys, ", ") + ")"
+			}
+			rows = append(rows, escapeRecord(row)+"\\l")
+		}
+		fmt.Fprintf(&builder, "    %q [label=\"{%s|%s}\"];\n", table.Name, escapeRecord(table.Name), strings.Join(rows, ""))
+	}
+
+	for _, relation := range d.Relations {
+		label := strings.Join(relation.ForeignKeys, ", ")
+		switch relation.Type {
+		case Many2Many:
+			fmt.Fprintf(&builder, "    %q -> %q [label=%q, arrowhead=crow];\n", relation.From, relation.JoinTable, label)
+			fmt.Fprintf(&builder, "    %q -> %q [label=%q, arrowhead=crow];\n", relation.To, relation.JoinTable, strings.Join(relation.JoinReferences, ", "))
+		case HasOne:
+			fmt.Fprintf(&builder, "    %q -> %q [label=%q, arrowhead=teeodot];\n", relation.From, relation.To, label)
+		default:
+			fmt.Fprintf(&builder, "    %q -> %q [label=%q, arrowhead=crow];\n", relation.From, relation.To, label)
+		}
+	}
+
+	builder.WriteString("}\n")
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// data dictionary dalam format Markdown
+func (d *Diagram) WriteMarkdown(w io.Writer) error {
+	var builder strings.Builder
+	builder.WriteString("# Data Dictionary\n\n")
+	builder.WriteString("<!-- generated from gorm models, do not edit -->\n")
+
+	for _, table := range d.Tables {
+		fmt.Fprintf(&builder, "\n## %s\n\n", table.Name)
+		if table.JoinTable {
+			builder.WriteString("Join table (many to many).\n\n")
+		} else {
+			fmt.Fprintf(&builder, "Model: `%s`\n\n", table.Model)
+		}
+
+		builder.WriteString("| Column | Type | Key | Nullable | Default | Comment |\n")
+		builder.WriteString("|---|---|---|---|---|---|\n")
+		for _, column := range table.Columns {
+			nullable := "no"
+			if column.Nullable {
+				nullable = "yes"
+			}
+			fmt.Fprintf(&builder, "| %s | %s | %s | %s | %s | %s |\n", column.Name, column.Type,
+				strings.Join(columnKeys(column), ", "), nullable, escapeMarkdown(column.Default), escapeMarkdown(column.Comment))
+		}
+
+		var relations []string
+		for _, relation := range d.Relations {
+			if description := describeRelation(table.Name, relation); description != "" {
+				relations = append(relations, description)
+			}
+		}
+		if len(relations) > 0 {
+			builder.WriteString("\nRelations:\n\n")
+			for _, relation := range relations {
+				builder.WriteString("- " + relation + "\n")
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, builder.String())
+	return err
+}
+
+// deskripsi relasi dari sudut pandang tabel
+func describeRelation(table string, relation Relation) string {
+	foreignKeys := strings.Join(relation.ForeignKeys, ", ")
+	switch {
+	case relation.Type == Many2Many && (relation.From == table || relation.To == table):
+		other := relation.To
+		if relation.To == table && relation.From != table {
+			other = relation.From
+		}
+		return fmt.Sprintf("many to many `%s` through `%s` (%s / %s)", other, relation.JoinTable,
+			foreignKeys, strings.Join(relation.JoinReferences, ", "))
+	case relation.Type == Many2Many && relation.JoinTable == table:
+		return fmt.Sprintf("joins `%s` (%s) and `%s` (%s)", relation.From, foreignKeys,
+			relation.To, strings.Join(relation.JoinReferences, ", "))
+	case relation.Type == Many2Many:
+		return ""
+	case relation.From == table:
+		kind := "has many"
+		if relation.Type == HasOne {
+			kind = "has one"
+		}
+		return fmt.Sprintf("%s `%s` (%s.%s)", kind, relation.To, relation.To, foreignKeys)
+	case relation.To == table:
+		return fmt.Sprintf("belongs to `%s` (%s)", relation.From, foreignKeys)
+	}
+
+	return ""
+}
+
+func columnKeys(column Column) []string {
+	var keys []string
+	if column.PrimaryKey {
+		keys = append(keys, "PK")
+	}
+	if column.ForeignKey {
+		keys = append(keys, "FK")
+	}
+	if column.Unique {
+		keys = append(keys, "UK")
+	}
+	return keys
+}
+
+func escapeRecord(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`, `"`, `\"`).Replace(value)
+}
+
+func escapeMarkdown(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
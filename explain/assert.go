@@ -0,0 +1,37 @@
+package explain
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// bagian dari *testing.T yang digunakan oleh AssertEfficient
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// menggagalkan test jika query plan memiliki peringatan selain jenis yang di izinkan
+// contoh :
+//
+//	explain.AssertEfficient(t, db, func(tx *gorm.DB) *gorm.DB {
+//		return tx.Where("id = ?", "1").Take(&user)
+//	}, explain.Filesort)
+//
+// catatan : pada tabel yang sangat kecil database bisa memilih full table scan walaupun index tersedia
+func AssertEfficient(t TestingT, db *gorm.DB, query func(tx *gorm.DB) *gorm.DB, allow ...WarningKind) *Plan {
+	t.Helper()
+
+	plan, err := Analyze(context.Background(), db, query)
+	if err != nil {
+		t.Errorf("explain: %v", err)
+		return nil
+	}
+
+	for _, warning := range plan.filterWarnings(allow) {
+		t.Errorf("explain: %s in query %s", warning, plan.SQL)
+	}
+
+	return plan
+}
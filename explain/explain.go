@@ -0,0 +1,325 @@
+// package explain menjalankan EXPLAIN untuk query gorm dan menormalisasi hasil nya menjadi struktur yang sama-
+// untuk mysql, postgres dan sqlite, sehingga query yang melakukan full table scan, filesort atau temporary table bisa dideteksi
+//
+// contoh :
+//
+//	plan, err := explain.Analyze(ctx, db, func(tx *gorm.DB) *gorm.DB {
+//		return tx.Model(&User{}).Joins("Wallet").Where("Wallet.balance > ?", 500000).Find(&users)
+//	})
+package explain
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type WarningKind string
+
+const (
+	FullTableScan  WarningKind = "full_table_scan"
+	Filesort       WarningKind = "filesort"
+	TemporaryTable WarningKind = "temporary_table"
+)
+
+// satu langkah pada query plan
+type Step struct {
+	Table  string `json:"table,omitempty"`
+	Access string `json:"access"` // jenis akses, contoh ALL / ref / const (mysql), Seq Scan (postgres), SCAN / SEARCH (sqlite)
+	Index  string `json:"index,omitempty"`
+	Rows   int64  `json:"rows"` // perkiraan jumlah baris, 0 jika tidak tersedia (sqlite)
+	Detail string `json:"detail,omitempty"`
+
+	FullScan  bool `json:"full_scan"`
+	Filesort  bool `json:"filesort"`
+	Temporary bool `json:"temporary"`
+}
+
+type Warning struct {
+	Kind   WarningKind `json:"kind"`
+	Table  string      `json:"table,omitempty"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+func (w Warning) String() string {
+	if w.Table == "" {
+		return string(w.Kind)
+	}
+	return fmt.Sprintf("%s on %s", w.Kind, w.Table)
+}
+
+// hasil EXPLAIN yang sudah di normalisasi
+type Plan struct {
+	Dialect  string        `json:"dialect"`
+	SQL      string        `json:"sql"`
+	Vars     []interface{} `json:"vars,omitempty"`
+	Steps    []Step        `json:"steps"`
+	Warnings []Warning     `json:"warnings"`
+}
+
+// true jika ada peringatan selain jenis yang di izinkan
+func (p *Plan) HasWarnings(allow ...WarningKind) bool {
+	return len(p.filterWarnings(allow)) > 0
+}
+
+func (p *Plan) filterWarnings(allow []WarningKind) []Warning {
+	var warnings []Warning
+	for _, warning := range p.Warnings {
+		allowed := false
+		for _, kind := range allow {
+			allowed = allowed || warning.Kind == kind
+		}
+		if !allowed {
+			warnings = append(warnings, warning)
+		}
+	}
+
+	return warnings
+}
+
+func (p *Plan) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s\n", p.SQL)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range p.Steps {
+		_, err = fmt.Fprintf(w, "  %-20s %-12s index=%-20s rows=%-8d %s\n", step.Table, step.Access, step.Index, step.Rows, step.Detail)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, warning := range p.Warnings {
+		_, err = fmt.Fprintf(w, "warning: %s\n", warning)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
+
+// mengambil sql dan parameter query gorm tanpa menjalankan nya (DryRun)
+func Capture(db *gorm.DB, query func(tx *gorm.DB) *gorm.DB) (string, []interface{}) {
+	tx := query(db.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true}))
+	return tx.Statement.SQL.String(), tx.Statement.Vars
+}
+
+// menjalankan EXPLAIN untuk query gorm
+func Analyze(ctx context.Context, db *gorm.DB, query func(tx *gorm.DB) *gorm.DB) (*Plan, error) {
+	sql, vars := Capture(db.WithContext(ctx), query)
+	return ExplainSQL(ctx, db, sql, vars...)
+}
+
+// menjalankan EXPLAIN untuk sql mentah, placeholder mengikuti dialect (? untuk mysql dan sqlite, $1 untuk postgres)
+func ExplainSQL(ctx context.Context, db *gorm.DB, query string, vars ...interface{}) (*Plan, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("explain: empty query")
+	}
+
+	dialect := db.Dialector.Name()
+	var prefix string
+	switch dialect {
+	case "mysql":
+		prefix = "EXPLAIN "
+	case "postgres":
+		prefix = "EXPLAIN (FORMAT JSON) "
+	case "sqlite":
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return nil, fmt.Errorf("explain: unsupported dialect %s", dialect)
+	}
+
+	// dijalankan langsung ke connection pool agar placeholder tidak di proses ulang oleh gorm
+	// menggunakan Statement.ConnPool, sehingga di dalam transaction EXPLAIN dijalankan pada koneksi transaction tersebut
+	rows, err := db.Statement.ConnPool.QueryContext(ctx, prefix+query, vars...)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+	defer rows.Close()
+
+	records, err := readRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+
+	plan := &Plan{Dialect: dialect, SQL: query, Vars: vars}
+	switch dialect {
+	case "mysql":
+		plan.Steps = MySQLSteps(records)
+	case "postgres":
+		if len(records) > 0 {
+			plan.Steps, err = PostgresSteps(records[0]["QUERY PLAN"])
+		}
+	case "sqlite":
+		plan.Steps = SQLiteSteps(records)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("explain: %w", err)
+	}
+
+	plan.Warnings = warnings(plan.Steps)
+	return plan, nil
+}
+
+// membaca semua baris hasil EXPLAIN menjadi map nama kolom => value (string)
+func readRows(rows *sql.Rows) ([]map[string]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		err = rows.Scan(pointers...)
+		if err != nil {
+			return nil, err
+		}
+
+		record := make(map[string]string, len(columns))
+		for i, column := range columns {
+			record[column] = values[i].String
+		}
+		records = append(records, record)
+	}
+
+	return records, rows.Err()
+}
+
+// normalisasi hasil EXPLAIN mysql (format tradisional)
+func MySQLSteps(records []map[string]string) []Step {
+	steps := make([]Step, 0, len(records))
+	for _, record := range records {
+		rows, _ := strconv.ParseInt(record["rows"], 10, 64)
+		extra := record["Extra"]
+
+		steps = append(steps, Step{
+			Table:     record["table"],
+			Access:    record["type"],
+			Index:     record["key"],
+			Rows:      rows,
+			Detail:    extra,
+			FullScan:  record["type"] == "ALL",
+			Filesort:  strings.Contains(extra, "Using filesort"),
+			Temporary: strings.Contains(extra, "Using temporary"),
+		})
+	}
+
+	return steps
+}
+
+// normalisasi hasil EXPLAIN (FORMAT JSON) postgres, node plan di baca secara rekursif
+func PostgresSteps(document string) ([]Step, error) {
+	var plans []struct {
+		Plan postgresNode `json:"Plan"`
+	}
+	err := json.Unmarshal([]byte(document), &plans)
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	var walk func(node postgresNode)
+	walk = func(node postgresNode) {
+		steps = append(steps, Step{
+			Table:     node.RelationName,
+			Access:    node.NodeType,
+			Index:     node.IndexName,
+			Rows:      int64(node.PlanRows),
+			Detail:    node.Filter,
+			FullScan:  node.NodeType == "Seq Scan",
+			Filesort:  node.NodeType == "Sort" || node.NodeType == "Incremental Sort",
+			Temporary: node.NodeType == "Materialize" || node.NodeType == "HashAggregate",
+		})
+		for _, child := range node.Plans {
+			walk(child)
+		}
+	}
+	for _, plan := range plans {
+		walk(plan.Plan)
+	}
+
+	return steps, nil
+}
+
+type postgresNode struct {
+	NodeType     string         `json:"Node Type"`
+	RelationName string         `json:"Relation Name"`
+	IndexName    string         `json:"Index Name"`
+	PlanRows     float64        `json:"Plan Rows"`
+	Filter       string         `json:"Filter"`
+	Plans        []postgresNode `json:"Plans"`
+}
+
+// normalisasi hasil EXPLAIN QUERY PLAN sqlite, contoh detail :
+// "SCAN users", "SEARCH wallets USING INDEX idx_wallets_user_id (user_id=?)", "USE TEMP B-TREE FOR ORDER BY"
+func SQLiteSteps(records []map[string]string) []Step {
+	steps := make([]Step, 0, len(records))
+	for _, record := range records {
+		detail := record["detail"]
+		fields := strings.Fields(detail)
+		step := Step{Detail: detail}
+
+		switch {
+		case len(fields) >= 2 && (fields[0] == "SCAN" || fields[0] == "SEARCH"):
+			step.Access = fields[0]
+			step.Table = fields[1]
+			if step.Table == "TABLE" && len(fields) >= 3 {
+				// sqlite versi lama : "SCAN TABLE users"
+				step.Table = fields[2]
+			}
+			if i := strings.Index(detail, "INDEX "); i >= 0 {
+				step.Index = strings.Fields(detail[i+len("INDEX "):])[0]
+			}
+			step.FullScan = fields[0] == "SCAN" && !strings.Contains(detail, "INDEX")
+		case strings.HasPrefix(detail, "USE TEMP B-TREE FOR ORDER BY"):
+			step.Access = "TEMP B-TREE"
+			step.Filesort = true
+		case strings.HasPrefix(detail, "USE TEMP B-TREE"):
+			step.Access = "TEMP B-TREE"
+			step.Temporary = true
+		default:
+			step.Access = detail
+		}
+
+		steps = append(steps, step)
+	}
+
+	return steps
+}
+
+func warnings(steps []Step) []Warning {
+	warnings := []Warning{}
+	for _, step := range steps {
+		if step.FullScan {
+			warnings = append(warnings, Warning{Kind: FullTableScan, Table: step.Table, Detail: step.Detail})
+		}
+		if step.Filesort {
+			warnings = append(warnings, Warning{Kind: Filesort, Table: step.Table, Detail: step.Detail})
+		}
+		if step.Temporary {
+			warnings = append(warnings, Warning{Kind: TemporaryTable, Table: step.Table, Detail: step.Detail})
+		}
+	}
+
+	return warnings
+}
@@ -0,0 +1,76 @@
+package explain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// implementasi normalisasi EXPLAIN mysql
+func TestMySQLSteps(t *testing.T) {
+	steps := MySQLSteps([]map[string]string{
+		{"table": "users", "type": "ALL", "key": "", "rows": "12", "Extra": "Using where; Using temporary; Using filesort"},
+		{"table": "Wallet", "type": "ref", "key": "fk_users_wallet", "rows": "1", "Extra": "Using where"},
+	})
+
+	assert.Equal(t, []Step{
+		{Table: "users", Access: "ALL", Rows: 12, Detail: "Using where; Using temporary; Using filesort", FullScan: true, Filesort: true, Temporary: true},
+		{Table: "Wallet", Access: "ref", Index: "fk_users_wallet", Rows: 1, Detail: "Using where"},
+	}, steps)
+
+	assert.Equal(t, []Warning{
+		{Kind: FullTableScan, Table: "users", Detail: steps[0].Detail},
+		{Kind: Filesort, Table: "users", Detail: steps[0].Detail},
+		{Kind: TemporaryTable, Table: "users", Detail: steps[0].Detail},
+	}, warnings(steps))
+}
+
+// implementasi normalisasi EXPLAIN (FORMAT JSON) postgres
+func TestPostgresSteps(t *testing.T) {
+	steps, err := PostgresSteps(`[{"Plan": {"Node Type": "Sort", "Plan Rows": 10, "Plans": [
+		{"Node Type": "Hash Join", "Plan Rows": 10, "Plans": [
+			{"Node Type": "Seq Scan", "Relation Name": "users", "Plan Rows": 100},
+			{"Node Type": "Index Scan", "Relation Name": "wallets", "Index Name": "idx_wallets_user_id", "Plan Rows": 1, "Filter": "(balance > 500000)"}
+		]}
+	]}}]`)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []Step{
+		{Access: "Sort", Rows: 10, Filesort: true},
+		{Access: "Hash Join", Rows: 10},
+		{Table: "users", Access: "Seq Scan", Rows: 100, FullScan: true},
+		{Table: "wallets", Access: "Index Scan", Index: "idx_wallets_user_id", Rows: 1, Detail: "(balance > 500000)"},
+	}, steps)
+
+	_, err = PostgresSteps("not json")
+	assert.NotNil(t, err)
+}
+
+// implementasi normalisasi EXPLAIN QUERY PLAN sqlite
+func TestSQLiteSteps(t *testing.T) {
+	steps := SQLiteSteps([]map[string]string{
+		{"detail": "SCAN users"},
+		{"detail": "SEARCH wallets USING INDEX idx_wallets_user_id (user_id=?)"},
+		{"detail": "SCAN TABLE products USING COVERING INDEX idx_products_name"},
+		{"detail": "USE TEMP B-TREE FOR GROUP BY"},
+		{"detail": "USE TEMP B-TREE FOR ORDER BY"},
+	})
+
+	assert.Equal(t, []Step{
+		{Table: "users", Access: "SCAN", Detail: "SCAN users", FullScan: true},
+		{Table: "wallets", Access: "SEARCH", Index: "idx_wallets_user_id", Detail: "SEARCH wallets USING INDEX idx_wallets_user_id (user_id=?)"},
+		{Table: "products", Access: "SCAN", Index: "idx_products_name", Detail: "SCAN TABLE products USING COVERING INDEX idx_products_name"},
+		{Access: "TEMP B-TREE", Detail: "USE TEMP B-TREE FOR GROUP BY", Temporary: true},
+		{Access: "TEMP B-TREE", Detail: "USE TEMP B-TREE FOR ORDER BY", Filesort: true},
+	}, steps)
+}
+
+// implementasi filter peringatan yang di izinkan
+func TestPlanHasWarnings(t *testing.T) {
+	plan := &Plan{Warnings: []Warning{{Kind: FullTableScan, Table: "users"}, {Kind: Filesort, Table: "users"}}}
+
+	assert.True(t, plan.HasWarnings())
+	assert.True(t, plan.HasWarnings(FullTableScan))
+	assert.False(t, plan.HasWarnings(FullTableScan, Filesort))
+	assert.Equal(t, "filesort on users", plan.Warnings[1].String())
+}
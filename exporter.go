@@ -0,0 +1,366 @@
+package belajar_go_lang_gorm
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi streaming export hasil query ke csv, ndjson dan excel (xlsx)
+// data diambil per batch menggunakan FindInBatches, sehingga penggunaan memory tetap kecil-
+// walaupun jumlah data nya besar, dan query tetap bisa menggunakan Preload() maupun Joins()
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"
+	ExportNDJSON ExportFormat = "ndjson"
+	ExportXLSX   ExportFormat = "xlsx"
+)
+
+// kolom yang di export, Path adalah lokasi field pada struct (termasuk embedded dan relasi)
+// contoh : {Header: "Nama Depan", Path: "Name.FirstName"} atau {Header: "Saldo", Path: "Wallet.Balance"}
+type ExportColumn struct {
+	Header string
+	Path   string
+}
+
+// pengaturan export
+type ExportOptions struct {
+	Format    ExportFormat
+	Columns   []ExportColumn // jika kosong, semua kolom database pada model akan di export
+	BatchSize int            // jumlah data per batch, default 1000
+}
+
+// menjalankan query dan menulis hasil nya ke w, mengembalikan jumlah baris yang di export
+// contoh : Export[User](ctx, db.Preload("Wallet"), w, ExportOptions{Format: ExportCSV})
+func Export[T any](ctx context.Context, query *gorm.DB, w io.Writer, opts ExportOptions) (int, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	columns := opts.Columns
+	if len(columns) == 0 {
+		var err error
+		columns, err = defaultExportColumns(new(T), query.NamingStrategy)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	writer, err := newExportWriter(opts.Format, w)
+	if err != nil {
+		return 0, err
+	}
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+
+	err = writer.WriteHeader(headers)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	var batch []T
+	result := query.WithContext(ctx).FindInBatches(&batch, opts.BatchSize, func(tx *gorm.DB, number int) error {
+		for i := range batch {
+			value := reflect.ValueOf(&batch[i]).Elem()
+
+			row := make([]interface{}, len(columns))
+			for j, column := range columns {
+				row[j] = fieldByPath(value, column.Path)
+			}
+
+			err := writer.WriteRow(row)
+			if err != nil {
+				return err
+			}
+			total++
+		}
+
+		return nil
+	})
+
+	if result.Error != nil {
+		return total, result.Error
+	}
+
+	return total, writer.Close()
+}
+
+// mengambil semua kolom database pada model, termasuk kolom dari embedded struct
+// kolom dengan tag 'sensitive' tidak di ikut sertakan
+func defaultExportColumns(model interface{}, namer schema.Namer) ([]ExportColumn, error) {
+	s, err := schema.Parse(model, &sync.Map{}, namer)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []ExportColumn
+	for _, field := range s.Fields {
+		// kolom sensitif (contoh password) tidak ikut di export
+		if _, sensitive := field.TagSettings["SENSITIVE"]; field.DBName == "" || sensitive {
+			continue
+		}
+
+		columns = append(columns, ExportColumn{Header: field.DBName, Path: strings.Join(field.BindNames, ".")})
+	}
+
+	return columns, nil
+}
+
+// mengambil value field berdasarkan path, contoh "Wallet.Balance"
+// jika di tengah path terdapat pointer nil (relasi tidak di preload), maka mengembalikan nil
+// untuk relasi has many, contoh "Addresses.Address", value setiap item digabung dengan pemisah "; "
+func fieldByPath(value reflect.Value, path string) interface{} {
+	names := strings.Split(path, ".")
+
+	for i, name := range names {
+		for value.Kind() == reflect.Pointer {
+			if value.IsNil() {
+				return nil
+			}
+			value = value.Elem()
+		}
+
+		if value.Kind() == reflect.Slice {
+			rest := strings.Join(names[i:], ".")
+			items := make([]string, 0, value.Len())
+			for j := 0; j < value.Len(); j++ {
+				items = append(items, formatExportValue(fieldByPath(value.Index(j), rest)))
+			}
+			return strings.Join(items, "; ")
+		}
+
+		if value.Kind() != reflect.Struct {
+			return nil
+		}
+
+		value = value.FieldByName(name)
+		if !value.IsValid() {
+			return nil
+		}
+	}
+
+	for value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	return value.Interface()
+}
+
+// mengubah value menjadi string untuk csv dan xlsx
+func formatExportValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if value.IsZero() {
+			return ""
+		}
+		return value.Format(time.RFC3339)
+	case gorm.DeletedAt:
+		if !value.Valid {
+			return ""
+		}
+		return value.Time.Format(time.RFC3339)
+	case fmt.Stringer:
+		return value.String()
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+type exportWriter interface {
+	WriteHeader(headers []string) error
+	WriteRow(row []interface{}) error
+	Close() error
+}
+
+func newExportWriter(format ExportFormat, w io.Writer) (exportWriter, error) {
+	switch format {
+	case ExportCSV, "":
+		return &csvExportWriter{writer: csv.NewWriter(w)}, nil
+	case ExportNDJSON:
+		return &ndjsonExportWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportXLSX:
+		return newXLSXExportWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writer csv
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func (c *csvExportWriter) WriteHeader(headers []string) error {
+	return c.writer.Write(headers)
+}
+
+func (c *csvExportWriter) WriteRow(row []interface{}) error {
+	record := make([]string, len(row))
+	for i, value := range row {
+		record[i] = formatExportValue(value)
+	}
+
+	return c.writer.Write(record)
+}
+
+func (c *csvExportWriter) Close() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// writer ndjson, satu object json per baris dengan header sebagai key
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+	headers []string
+}
+
+func (n *ndjsonExportWriter) WriteHeader(headers []string) error {
+	n.headers = headers
+	return nil
+}
+
+func (n *ndjsonExportWriter) WriteRow(row []interface{}) error {
+	object := make(map[string]interface{}, len(row))
+	for i, value := range row {
+		object[n.headers[i]] = value
+	}
+
+	return n.encoder.Encode(object)
+}
+
+func (n *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// writer xlsx, file xlsx adalah zip yang berisi beberapa file xml
+// sheet di tulis secara streaming, file xml lain nya di tulis ketika Close()
+type xlsxExportWriter struct {
+	zip   *zip.Writer
+	sheet io.Writer
+	row   int
+}
+
+func newXLSXExportWriter(w io.Writer) (*xlsxExportWriter, error) {
+	archive := zip.NewWriter(w)
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = io.WriteString(sheet, xml.Header+
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxExportWriter{zip: archive, sheet: sheet}, nil
+}
+
+func (x *xlsxExportWriter) WriteHeader(headers []string) error {
+	row := make([]interface{}, len(headers))
+	for i, header := range headers {
+		row[i] = header
+	}
+
+	return x.WriteRow(row)
+}
+
+func (x *xlsxExportWriter) WriteRow(row []interface{}) error {
+	x.row++
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, `<row r="%d">`, x.row)
+
+	for i, value := range row {
+		ref := xlsxColumnName(i) + strconv.Itoa(x.row)
+
+		switch value := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			fmt.Fprintf(&builder, `<c r="%s"><v>%v</v></c>`, ref, value)
+		default:
+			builder.WriteString(`<c r="` + ref + `" t="inlineStr"><is><t>`)
+			xml.EscapeText(&builder, []byte(formatExportValue(value)))
+			builder.WriteString(`</t></is></c>`)
+		}
+	}
+
+	builder.WriteString(`</row>`)
+	_, err := io.WriteString(x.sheet, builder.String())
+	return err
+}
+
+func (x *xlsxExportWriter) Close() error {
+	_, err := io.WriteString(x.sheet, `</sheetData></worksheet>`)
+	if err != nil {
+		return err
+	}
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+			`</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+			`</Relationships>`},
+	}
+
+	for _, file := range files {
+		writer, err := x.zip.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.WriteString(writer, xml.Header+file.content)
+		if err != nil {
+			return err
+		}
+	}
+
+	return x.zip.Close()
+}
+
+// mengubah index kolom menjadi nama kolom excel, contoh 0 => A, 26 => AA
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+
+	return name
+}
@@ -0,0 +1,201 @@
+// package factory berisi factory untuk membuat data model pada pengujian
+//
+// contoh :
+//
+//	product := factory.Product().Create(t, db)
+//	user := factory.User(factory.Sultan).WithAddresses(3).LikesProducts(product).Create(t, db)
+//
+// id dibuat dari sequence yang unik per proses, relasi (wallet, addresses, like products) di hubungkan otomatis,-
+// dan data yang dibuat dengan Create akan dihapus kembali ketika test selesai (t.Cleanup)
+package factory
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	sequenceMu sync.Mutex
+	sequences  = map[string]int64{}
+
+	// prefix id per proses, agar id tidak bentrok dengan data dari proses test sebelum nya
+	runID = strconv.FormatInt(time.Now().UnixNano()%(1<<40), 36)
+)
+
+// nomor urut berikutnya untuk nama sequence tertentu, dimulai dari 1
+func Sequence(name string) int64 {
+	sequenceMu.Lock()
+	defer sequenceMu.Unlock()
+
+	sequences[name]++
+	return sequences[name]
+}
+
+// id unik, contoh "user-k3j9x2a1-1"
+func sequenceID(name string) string {
+	return fmt.Sprintf("%s-%s-%d", name, runID, Sequence(name))
+}
+
+// trait user, kumpulan pengaturan yang bisa digunakan ulang
+type UserTrait func(f *UserFactory)
+
+// user dengan wallet berisi 1.000.000.000
+func Sultan(f *UserFactory) {
+	f.WithWallet(1_000_000_000)
+}
+
+// user dengan wallet kosong
+func Broke(f *UserFactory) {
+	f.WithWallet(0)
+}
+
+type UserFactory struct {
+	user      belajar_go_lang_gorm.User
+	wallet    *int64
+	addresses int
+	products  []belajar_go_lang_gorm.Product
+}
+
+// membuat factory user dengan nilai default, kemudian menerapkan traits
+func User(traits ...UserTrait) *UserFactory {
+	number := Sequence("user-name")
+	f := &UserFactory{
+		user: belajar_go_lang_gorm.User{
+			ID:       sequenceID("user"),
+			Password: "rahasia",
+			Name:     belajar_go_lang_gorm.Name{FirstName: "User " + strconv.FormatInt(number, 10)},
+		},
+	}
+
+	for _, trait := range traits {
+		trait(f)
+	}
+
+	return f
+}
+
+// mengubah field user secara langsung, contoh : With(func(u *User) { u.Name.LastName = "Khannedy" })
+func (f *UserFactory) With(fn func(user *belajar_go_lang_gorm.User)) *UserFactory {
+	fn(&f.user)
+	return f
+}
+
+// menambahkan wallet dengan saldo tertentu
+func (f *UserFactory) WithWallet(balance int64) *UserFactory {
+	f.wallet = &balance
+	return f
+}
+
+// menambahkan sejumlah address
+func (f *UserFactory) WithAddresses(count int) *UserFactory {
+	f.addresses = count
+	return f
+}
+
+// menambahkan product yang di sukai user (relasi many to many)
+func (f *UserFactory) LikesProducts(products ...belajar_go_lang_gorm.Product) *UserFactory {
+	f.products = append(f.products, products...)
+	return f
+}
+
+// membuat user beserta relasi nya tanpa menyimpan ke database
+func (f *UserFactory) Build() belajar_go_lang_gorm.User {
+	user := f.user
+
+	if f.wallet != nil {
+		user.Wallet = belajar_go_lang_gorm.Wallet{
+			ID:      sequenceID("wallet"),
+			UserId:  user.ID,
+			Balance: *f.wallet,
+		}
+	}
+
+	user.Addresses = nil
+	for i := 1; i <= f.addresses; i++ {
+		user.Addresses = append(user.Addresses, belajar_go_lang_gorm.Address{
+			UserId:  user.ID,
+			Address: fmt.Sprintf("Alamat %d %s", i, user.Name.FirstName),
+		})
+	}
+
+	user.LikeProducts = append([]belajar_go_lang_gorm.Product(nil), f.products...)
+	return user
+}
+
+// menyimpan user beserta relasi nya, data dihapus kembali ketika test selesai
+func (f *UserFactory) Create(t testing.TB, db *gorm.DB) belajar_go_lang_gorm.User {
+	t.Helper()
+
+	user := f.Build()
+	err := db.Create(&user).Error
+	if err != nil {
+		t.Fatalf("factory: create user: %v", err)
+	}
+
+	t.Cleanup(func() {
+		// menghapus wallet, addresses dan data tabel penghubung (like products, roles)
+		err := db.Select(clause.Associations).Delete(&user).Error
+		if err != nil {
+			t.Errorf("factory: cleanup user %s: %v", user.ID, err)
+		}
+	})
+
+	return user
+}
+
+type ProductFactory struct {
+	product belajar_go_lang_gorm.Product
+}
+
+// membuat factory product dengan nilai default
+func Product() *ProductFactory {
+	id := sequenceID("product")
+	return &ProductFactory{
+		product: belajar_go_lang_gorm.Product{
+			ID:    id,
+			Name:  "Product " + id,
+			Price: 10_000,
+		},
+	}
+}
+
+func (f *ProductFactory) WithPrice(price int64) *ProductFactory {
+	f.product.Price = price
+	return f
+}
+
+func (f *ProductFactory) With(fn func(product *belajar_go_lang_gorm.Product)) *ProductFactory {
+	fn(&f.product)
+	return f
+}
+
+func (f *ProductFactory) Build() belajar_go_lang_gorm.Product {
+	return f.product
+}
+
+// menyimpan product, data dihapus kembali ketika test selesai
+func (f *ProductFactory) Create(t testing.TB, db *gorm.DB) belajar_go_lang_gorm.Product {
+	t.Helper()
+
+	product := f.Build()
+	err := db.Create(&product).Error
+	if err != nil {
+		t.Fatalf("factory: create product: %v", err)
+	}
+
+	t.Cleanup(func() {
+		err := db.Select(clause.Associations).Delete(&product).Error
+		if err != nil {
+			t.Errorf("factory: cleanup product %s: %v", product.ID, err)
+		}
+	})
+
+	return product
+}
@@ -0,0 +1,50 @@
+package factory
+
+import (
+	"testing"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func openConnection(t *testing.T) *gorm.DB {
+	keyring, err := belajar_go_lang_gorm.NewKeyring("test-1", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+	})
+	assert.Nil(t, err)
+	belajar_go_lang_gorm.SetEncryptionKeyring(keyring)
+
+	db, err := gorm.Open(mysql.Open("root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// implementasi factory user beserta relasi nya
+func TestUserFactory(t *testing.T) {
+	db := openConnection(t)
+
+	first := Product().WithPrice(5_000).Create(t, db)
+	second := Product().Create(t, db)
+
+	user := User(Sultan).WithAddresses(3).LikesProducts(first, second).Create(t, db)
+
+	var result belajar_go_lang_gorm.User
+	err := db.Preload("Wallet").Preload("Addresses").Preload("LikeProducts").Take(&result, "id = ?", user.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1_000_000_000), result.Wallet.Balance)
+	assert.Equal(t, 3, len(result.Addresses))
+	assert.Equal(t, 2, len(result.LikeProducts))
+
+	// sequence membuat id yang berbeda untuk setiap user
+	other := User(Broke).With(func(u *belajar_go_lang_gorm.User) {
+		u.Name.LastName = "Factory"
+	}).Build()
+	assert.NotEqual(t, user.ID, other.ID)
+	assert.Equal(t, int64(0), other.Wallet.Balance)
+	assert.Equal(t, "Factory", other.Name.LastName)
+}
@@ -0,0 +1,222 @@
+// package fixtures memuat data dari file yaml ke database
+//
+// setiap file berisi nama tabel sebagai key dan daftar baris (nama kolom => value), contoh :
+//
+//	users:
+//	  - id: fixture-1
+//	    password: rahasia
+//	    first_name: Fixture
+//	wallets:
+//	  - id: fixture-wallet-1
+//	    user_id: fixture-1
+//	    balance: 1000000
+//	user_like_product:
+//	  - user_id: fixture-1
+//	    product_id: P001
+//
+// urutan insert ditentukan dari relasi pada schema gorm (belongs to, has one, has many, many to many),-
+// sehingga tabel yang di referensikan selalu di isi lebih dulu, tidak bergantung pada urutan di file
+// data di simpan menggunakan upsert (clause.OnConflict), sehingga bisa dijalankan berulang kali di dialect apapun
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// data fixture, nama tabel => daftar baris
+type Data map[string][]map[string]interface{}
+
+type Loader struct {
+	schemas      map[string]*schema.Schema
+	dependencies map[string]map[string]bool // tabel => tabel yang harus di isi lebih dulu
+}
+
+// membuat loader dari daftar model, tabel penghubung many to many dikenali otomatis dari relasi
+func NewLoader(db *gorm.DB, models ...interface{}) (*Loader, error) {
+	loader := &Loader{
+		schemas:      map[string]*schema.Schema{},
+		dependencies: map[string]map[string]bool{},
+	}
+
+	cache := &sync.Map{}
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+		loader.schemas[s.Table] = s
+	}
+
+	for table, s := range loader.schemas {
+		for _, relationship := range s.Relationships.Relations {
+			other := relationship.FieldSchema.Table
+			switch relationship.Type {
+			case schema.BelongsTo:
+				loader.depend(table, other)
+			case schema.HasOne, schema.HasMany:
+				loader.depend(other, table)
+			case schema.Many2Many:
+				loader.depend(relationship.JoinTable.Table, table)
+				loader.depend(relationship.JoinTable.Table, other)
+			}
+		}
+	}
+
+	return loader, nil
+}
+
+func (l *Loader) depend(table string, dependency string) {
+	if table == dependency {
+		return
+	}
+	if l.dependencies[table] == nil {
+		l.dependencies[table] = map[string]bool{}
+	}
+	l.dependencies[table][dependency] = true
+}
+
+// mengurutkan tabel berdasarkan dependency (topological sort)
+// tabel tanpa hubungan diurutkan berdasarkan nama agar hasil nya selalu sama
+func (l *Loader) Order(tables []string) ([]string, error) {
+	wanted := map[string]bool{}
+	for _, table := range tables {
+		wanted[table] = true
+	}
+
+	sorted := append([]string(nil), tables...)
+	sort.Strings(sorted)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var result []string
+
+	var visit func(table string) error
+	visit = func(table string) error {
+		switch state[table] {
+		case visiting:
+			return fmt.Errorf("fixtures: circular dependency on table %s", table)
+		case visited:
+			return nil
+		}
+
+		state[table] = visiting
+		dependencies := make([]string, 0, len(l.dependencies[table]))
+		for dependency := range l.dependencies[table] {
+			dependencies = append(dependencies, dependency)
+		}
+		sort.Strings(dependencies)
+
+		for _, dependency := range dependencies {
+			if !wanted[dependency] {
+				continue
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		state[table] = visited
+		result = append(result, table)
+		return nil
+	}
+
+	for _, table := range sorted {
+		if err := visit(table); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// membaca file yaml dan memuat isi nya ke database, isi beberapa file digabung terlebih dahulu
+func (l *Loader) LoadFiles(ctx context.Context, db *gorm.DB, paths ...string) error {
+	data := Data{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		var file Data
+		err = yaml.Unmarshal(content, &file)
+		if err != nil {
+			return fmt.Errorf("fixtures: %s: %w", path, err)
+		}
+
+		for table, rows := range file {
+			data[table] = append(data[table], rows...)
+		}
+	}
+
+	return l.Load(ctx, db, data)
+}
+
+// memuat data ke database di dalam satu transaction
+func (l *Loader) Load(ctx context.Context, db *gorm.DB, data Data) error {
+	tables := make([]string, 0, len(data))
+	for table := range data {
+		tables = append(tables, table)
+	}
+
+	ordered, err := l.Order(tables)
+	if err != nil {
+		return err
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, table := range ordered {
+			rows := data[table]
+			if len(rows) == 0 {
+				continue
+			}
+
+			err := l.insert(ctx, tx, table, rows)
+			if err != nil {
+				return fmt.Errorf("fixtures: table %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+func (l *Loader) insert(ctx context.Context, tx *gorm.DB, table string, rows []map[string]interface{}) error {
+	s, ok := l.schemas[table]
+	if !ok {
+		// tabel penghubung atau tabel tanpa model, di insert langsung sebagai map
+		return tx.Table(table).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+	}
+
+	// tabel dengan model di insert melalui struct, agar hook, serializer dan timestamp tetap berjalan
+	values := reflect.MakeSlice(reflect.SliceOf(s.ModelType), len(rows), len(rows))
+	for i, row := range rows {
+		for column, value := range row {
+			field := s.LookUpField(column)
+			if field == nil || field.DBName == "" {
+				return fmt.Errorf("unknown column %q", column)
+			}
+
+			err := field.Set(ctx, values.Index(i), value)
+			if err != nil {
+				return fmt.Errorf("column %q: %w", column, err)
+			}
+		}
+	}
+
+	pointer := reflect.New(values.Type())
+	pointer.Elem().Set(values)
+	return tx.Omit(clause.Associations).Clauses(clause.OnConflict{UpdateAll: true}).Create(pointer.Interface()).Error
+}
@@ -0,0 +1,50 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+
+	belajar_go_lang_gorm "belajar-go-lang-gorm"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// implementasi fixtures yaml, urutan insert mengikuti relasi pada schema
+func TestLoadFiles(t *testing.T) {
+	keyring, err := belajar_go_lang_gorm.NewKeyring("test-1", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+	})
+	assert.Nil(t, err)
+	belajar_go_lang_gorm.SetEncryptionKeyring(keyring)
+
+	db, err := gorm.Open(mysql.Open("root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := NewLoader(db, &belajar_go_lang_gorm.User{}, &belajar_go_lang_gorm.Wallet{},
+		&belajar_go_lang_gorm.Address{}, &belajar_go_lang_gorm.Product{})
+	assert.Nil(t, err)
+
+	order, err := loader.Order([]string{"user_like_product", "wallets", "products", "users"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"products", "users", "user_like_product", "wallets"}, order)
+
+	// dijalankan dua kali, data yang sudah ada akan di update (upsert)
+	for i := 0; i < 2; i++ {
+		err = loader.LoadFiles(context.Background(), db, "testdata/users.yml", "testdata/products.yml")
+		assert.Nil(t, err)
+	}
+
+	var user belajar_go_lang_gorm.User
+	err = db.Preload("Wallet").Preload("LikeProducts").Take(&user, "id = ?", "fixture-user-1").Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000000), user.Wallet.Balance)
+	assert.Equal(t, 1, len(user.LikeProducts))
+
+	t.Cleanup(func() {
+		db.Select("Wallet", "Addresses", "LikeProducts").Delete(&user)
+		db.Delete(&belajar_go_lang_gorm.Product{}, "id = ?", "fixture-product-1")
+	})
+}
@@ -0,0 +1,468 @@
+package belajar_go_lang_gorm
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// implementasi hak akses dan hak penghapusan data pribadi (GDPR)
+// ExportUserData mengumpulkan semua data milik seseorang ke dalam file zip berisi json,-
+// EraseUser menghapus atau menganonimkan data tersebut sesuai ErasurePolicy dan mencatat audit di tabel erasure_audits
+//
+// tabel users tidak memiliki kolom email, sehingga email yang digunakan pada guest book harus diberikan oleh pemanggil
+type ErasureAction string
+
+const (
+	ErasureDelete    ErasureAction = "delete"    // data di hapus permanen
+	ErasureAnonymize ErasureAction = "anonymize" // data tetap ada, tetapi data pribadi dikosongkan dan user_id diganti pseudonym
+	ErasureKeep      ErasureAction = "keep"      // data tidak diubah
+)
+
+// aturan penghapusan untuk setiap jenis data
+// jika User di hapus, relasi user juga mengikuti delete policy pada model User (lihat delete_policy.go),-
+// sehingga keep tidak bisa digunakan untuk relasi yang memiliki delete policy (wallet, addresses, liked_products dan todos)
+//
+// data wallet, addresses dan todos yang di anonimkan dipindahkan ke user tombstone (id = pseudonym, tanpa data pribadi),-
+// agar foreign key user_id tetap valid
+type ErasurePolicy struct {
+	User          ErasureAction // delete atau anonymize
+	Wallet        ErasureAction // delete hanya bisa jika saldo 0, agar total saldo (ledger) tidak berubah
+	Addresses     ErasureAction
+	LikedProducts ErasureAction // delete atau keep
+	Todos         ErasureAction // termasuk todo yang sudah di soft delete
+	UserLogs      ErasureAction
+	GuestBooks    ErasureAction
+}
+
+// policy default : data pribadi di hapus, wallet dan user log di anonimkan agar total saldo dan statistik tetap benar
+func DefaultErasurePolicy() ErasurePolicy {
+	return ErasurePolicy{
+		User:          ErasureDelete,
+		Wallet:        ErasureAnonymize,
+		Addresses:     ErasureDelete,
+		LikedProducts: ErasureDelete,
+		Todos:         ErasureDelete,
+		UserLogs:      ErasureAnonymize,
+		GuestBooks:    ErasureDelete,
+	}
+}
+
+var ErrLedgerImbalance = errors.New("erasure would change wallet ledger total")
+
+// catatan audit penghapusan data, tidak menyimpan user id asli
+type ErasureAudit struct {
+	ID          int64     `gorm:"primary_key;column:id;autoIncrement"`
+	SubjectHash string    `gorm:"column:subject_hash;size:64;index"` // hmac sha256 dari user id (lihat ErasureSubjectHash)
+	Pseudonym   string    `gorm:"column:pseudonym;size:100"`         // pengganti user_id pada data yang di anonimkan
+	Summary     string    `gorm:"column:summary;type:text"`          // json daftar ErasureStep
+	LedgerTotal int64     `gorm:"column:ledger_total"`               // total saldo wallet sebelum dan sesudah penghapusan
+	ErasedAt    time.Time `gorm:"column:erased_at;autoCreateTime"`
+}
+
+// menentukan nama table
+func (a ErasureAudit) TableName() string {
+	return "erasure_audits"
+}
+
+// hasil penghapusan untuk satu jenis data
+type ErasureStep struct {
+	Data   string        `json:"data"`
+	Action ErasureAction `json:"action"`
+	Rows   int64         `json:"rows"`
+}
+
+type UserDataPrivacy struct {
+	db     *gorm.DB
+	policy ErasurePolicy
+}
+
+func NewUserDataPrivacy(db *gorm.DB, policy ErasurePolicy) *UserDataPrivacy {
+	return &UserDataPrivacy{db: db, policy: policy}
+}
+
+// hash user id yang disimpan pada audit, menggunakan hmac sha256 dengan key turunan dari active key keyring
+// sha256 biasa tidak cukup karena user id mudah ditebak (contoh "1"), sehingga hash nya bisa dicari dengan brute force
+func ErasureSubjectHash(userID string) (string, error) {
+	keyring, err := currentKeyring()
+	if err != nil {
+		return "", err
+	}
+
+	return keyring.subjectHash(keyring.active, userID), nil
+}
+
+// mencari audit user tertentu, hash dibuat dengan semua key pada keyring agar audit lama tetap ditemukan setelah rotasi key
+func ErasureSubjectEquals(userID string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		keyring, err := currentKeyring()
+		if err != nil {
+			db.AddError(err)
+			return db
+		}
+
+		hashes := make([]interface{}, 0, len(keyring.subjectKeys))
+		for id := range keyring.subjectKeys {
+			hashes = append(hashes, keyring.subjectHash(id, userID))
+		}
+
+		return db.Where(clause.IN{Column: clause.Column{Name: "subject_hash"}, Values: hashes})
+	}
+}
+
+func (k *Keyring) subjectHash(id string, userID string) string {
+	mac := hmac.New(sha256.New, k.subjectKeys[id])
+	mac.Write([]byte(userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// mengumpulkan data user ke dalam zip berisi file json :
+// user.json, wallet.json, addresses.json, liked_products.json, todos.json, user_logs.json, guest_books.json dan manifest.json
+// kolom dengan tag sensitive (contoh password) tidak ikut di export
+func (p *UserDataPrivacy) ExportUserData(ctx context.Context, userID string, emails ...string) ([]byte, error) {
+	files := map[string]interface{}{}
+	counts := map[string]int{}
+
+	// dibaca di dalam satu transaction agar data yang di export konsisten
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Take(&user, "id = ?", userID).Error
+		if err != nil {
+			return err
+		}
+
+		var wallets []Wallet
+		var addresses []Address
+		var products []Product
+		var todos []Todo
+		var logs []UserLog
+		var guestBooks []GuestBook
+
+		queries := []error{
+			tx.Where("user_id = ?", userID).Limit(1).Find(&wallets).Error,
+			tx.Where("user_id = ?", userID).Order("id").Find(&addresses).Error,
+			tx.Model(&user).Order("products.id").Association("LikeProducts").Find(&products),
+			tx.Unscoped().Where("user_id = ?", userID).Order("id").Find(&todos).Error,
+			tx.Where("user_id = ?", userID).Order("id").Find(&logs).Error,
+		}
+		if len(emails) > 0 {
+			queries = append(queries, tx.Where("email IN ?", emails).Order("id").Find(&guestBooks).Error)
+		}
+		for _, err := range queries {
+			if err != nil {
+				return err
+			}
+		}
+
+		values := map[string]interface{}{
+			"user.json":           []User{user},
+			"wallet.json":         wallets,
+			"addresses.json":      addresses,
+			"liked_products.json": products,
+			"todos.json":          todos,
+			"user_logs.json":      logs,
+			"guest_books.json":    guestBooks,
+		}
+		for name, value := range values {
+			records, err := personalRecords(ctx, tx, value)
+			if err != nil {
+				return err
+			}
+			counts[name] = len(records)
+			files[name] = records
+		}
+
+		// user dan wallet berupa satu object, bukan array
+		files["user.json"] = files["user.json"].([]map[string]interface{})[0]
+		if wallet := files["wallet.json"].([]map[string]interface{}); len(wallet) > 0 {
+			files["wallet.json"] = wallet[0]
+		} else {
+			files["wallet.json"] = nil
+		}
+
+		files["manifest.json"] = map[string]interface{}{
+			"user_id":     userID,
+			"emails":      emails,
+			"exported_at": tx.NowFunc(),
+			"records":     counts,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+	for _, name := range []string{"manifest.json", "user.json", "wallet.json", "addresses.json", "liked_products.json",
+		"todos.json", "user_logs.json", "guest_books.json"} {
+		data, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := archive.Create(name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = file.Write(data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// mengubah slice model menjadi map nama kolom => value, tanpa kolom sensitive
+func personalRecords(ctx context.Context, db *gorm.DB, values interface{}) ([]map[string]interface{}, error) {
+	stmt := &gorm.Statement{DB: db}
+	err := stmt.Parse(values)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := reflect.ValueOf(values)
+	records := make([]map[string]interface{}, 0, rows.Len())
+	for i := 0; i < rows.Len(); i++ {
+		record := map[string]interface{}{}
+		for _, field := range stmt.Schema.Fields {
+			if _, sensitive := field.TagSettings["SENSITIVE"]; sensitive || field.DBName == "" {
+				continue
+			}
+
+			record[field.DBName], _ = field.ValueOf(ctx, rows.Index(i))
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// menghapus atau menganonimkan data user sesuai policy di dalam satu transaction, dan mencatat audit nya
+// total saldo wallet (ledger) sebelum dan sesudah penghapusan harus sama, jika tidak maka semua perubahan di batalkan
+func (p *UserDataPrivacy) EraseUser(ctx context.Context, userID string, emails ...string) (*ErasureAudit, error) {
+	err := p.policy.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonym, err := newPseudonym()
+	if err != nil {
+		return nil, err
+	}
+
+	var audit *ErasureAudit
+	err = p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user User
+		err := tx.Take(&user, "id = ?", userID).Error
+		if err != nil {
+			return err
+		}
+
+		before, err := ledgerTotal(tx)
+		if err != nil {
+			return err
+		}
+
+		if p.policy.needsTombstone() {
+			tombstone := User{ID: pseudonym}
+			err = tx.Omit(clause.Associations).Create(&tombstone).Error
+			if err != nil {
+				return fmt.Errorf("erase: create tombstone user: %w", err)
+			}
+		}
+
+		var steps []ErasureStep
+		erase := func(data string, action ErasureAction, run func() *gorm.DB) error {
+			if action == ErasureKeep {
+				steps = append(steps, ErasureStep{Data: data, Action: action})
+				return nil
+			}
+
+			result := run()
+			if result.Error != nil {
+				return fmt.Errorf("erase %s: %w", data, result.Error)
+			}
+			steps = append(steps, ErasureStep{Data: data, Action: action, Rows: result.RowsAffected})
+			return nil
+		}
+
+		byUser := func(model interface{}) *gorm.DB {
+			return tx.Model(model).Where("user_id = ?", userID)
+		}
+		anonymizeOrDelete := func(model interface{}, action ErasureAction, columns map[string]interface{}) func() *gorm.DB {
+			return func() *gorm.DB {
+				if action == ErasureDelete {
+					return byUser(model).Unscoped().Delete(model)
+				}
+
+				columns["user_id"] = pseudonym
+				return byUser(model).Unscoped().UpdateColumns(columns)
+			}
+		}
+
+		err = erase("liked_products", p.policy.LikedProducts, func() *gorm.DB {
+			return tx.Table("user_like_product").Where("user_id = ?", userID).Delete(map[string]interface{}{})
+		})
+		if err != nil {
+			return err
+		}
+
+		err = erase("addresses", p.policy.Addresses, anonymizeOrDelete(&Address{}, p.policy.Addresses,
+			map[string]interface{}{"address": ""}))
+		if err != nil {
+			return err
+		}
+
+		err = erase("todos", p.policy.Todos, anonymizeOrDelete(&Todo{}, p.policy.Todos,
+			map[string]interface{}{"title": "", "description": ""}))
+		if err != nil {
+			return err
+		}
+
+		err = erase("user_logs", p.policy.UserLogs, anonymizeOrDelete(&UserLog{}, p.policy.UserLogs,
+			map[string]interface{}{"payload": nil}))
+		if err != nil {
+			return err
+		}
+
+		err = erase("guest_books", p.policy.GuestBooks, func() *gorm.DB {
+			// tanpa email tidak ada data guest book yang bisa di cari
+			if len(emails) == 0 {
+				return tx
+			}
+
+			query := tx.Model(&GuestBook{}).Where("email IN ?", emails)
+			if p.policy.GuestBooks == ErasureDelete {
+				return query.Delete(&GuestBook{})
+			}
+			return query.UpdateColumns(map[string]interface{}{"name": "", "email": "", "message": ""})
+		})
+		if err != nil {
+			return err
+		}
+
+		err = erase("wallet", p.policy.Wallet, anonymizeOrDelete(&Wallet{}, p.policy.Wallet, map[string]interface{}{}))
+		if err != nil {
+			return err
+		}
+
+		err = erase("user", p.policy.User, func() *gorm.DB {
+			if p.policy.User == ErasureDelete {
+				return tx.Delete(&user)
+			}
+			return tx.Model(&user).UpdateColumns(map[string]interface{}{
+				"password": "", "first_name": "", "middle_name": "", "last_name": "",
+			})
+		})
+		if err != nil {
+			return err
+		}
+
+		after, err := ledgerTotal(tx)
+		if err != nil {
+			return err
+		}
+		if before != after {
+			return fmt.Errorf("%w: %d before, %d after", ErrLedgerImbalance, before, after)
+		}
+
+		summary, err := json.Marshal(steps)
+		if err != nil {
+			return err
+		}
+
+		subjectHash, err := ErasureSubjectHash(userID)
+		if err != nil {
+			return err
+		}
+
+		audit = &ErasureAudit{
+			SubjectHash: subjectHash,
+			Pseudonym:   pseudonym,
+			Summary:     string(summary),
+			LedgerTotal: after,
+		}
+		return tx.Create(audit).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return audit, nil
+}
+
+func (p ErasurePolicy) validate() error {
+	rules := []struct {
+		data     string
+		action   ErasureAction
+		allowed  []ErasureAction
+		relation string // relasi pada User yang memiliki delete policy
+	}{
+		{"user", p.User, []ErasureAction{ErasureDelete, ErasureAnonymize}, ""},
+		{"wallet", p.Wallet, []ErasureAction{ErasureDelete, ErasureAnonymize, ErasureKeep}, "Wallet"},
+		{"addresses", p.Addresses, []ErasureAction{ErasureDelete, ErasureAnonymize, ErasureKeep}, "Addresses"},
+		{"liked_products", p.LikedProducts, []ErasureAction{ErasureDelete, ErasureKeep}, "LikeProducts"},
+		{"todos", p.Todos, []ErasureAction{ErasureDelete, ErasureAnonymize, ErasureKeep}, "Todos"},
+		{"user_logs", p.UserLogs, []ErasureAction{ErasureDelete, ErasureAnonymize, ErasureKeep}, ""},
+		{"guest_books", p.GuestBooks, []ErasureAction{ErasureDelete, ErasureAnonymize, ErasureKeep}, ""},
+	}
+
+	for _, rule := range rules {
+		valid := false
+		for _, action := range rule.allowed {
+			valid = valid || rule.action == action
+		}
+		if !valid {
+			return fmt.Errorf("erasure policy: action %q is not allowed for %s", rule.action, rule.data)
+		}
+
+		// data yang di keep akan tetap di proses delete policy ketika user di hapus (restrict, cascade atau soft_cascade)
+		if rule.action == ErasureKeep && rule.relation != "" && p.User == ErasureDelete {
+			return fmt.Errorf("erasure policy: %s can not be kept when user is deleted, it follows the delete policy of User.%s", rule.data, rule.relation)
+		}
+	}
+
+	return nil
+}
+
+// true jika ada data dengan foreign key ke users yang di anonimkan
+func (p ErasurePolicy) needsTombstone() bool {
+	return p.Wallet == ErasureAnonymize || p.Addresses == ErasureAnonymize || p.Todos == ErasureAnonymize
+}
+
+// total saldo seluruh wallet
+func ledgerTotal(tx *gorm.DB) (int64, error) {
+	var total int64
+	err := tx.Model(&Wallet{}).Select("COALESCE(SUM(balance), 0)").Scan(&total).Error
+	return total, err
+}
+
+func newPseudonym() (string, error) {
+	random := make([]byte, 8)
+	_, err := rand.Read(random)
+	if err != nil {
+		return "", err
+	}
+
+	return "erased-" + hex.EncodeToString(random), nil
+}
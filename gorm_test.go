@@ -1,17 +1,42 @@
 package belajar_go_lang_gorm
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"encoding/json"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"belajar-go-lang-gorm/erd"
+	"belajar-go-lang-gorm/explain"
+	"belajar-go-lang-gorm/integrity"
+	"belajar-go-lang-gorm/modelgen"
+	"belajar-go-lang-gorm/schemadiff"
+	"belajar-go-lang-gorm/scopes"
+	mysqlDriver "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 // implementasi database connection
@@ -19,10 +44,36 @@ import (
 func OpenConnection() *gorm.DB {
 	// membuat destinasi untuk database yang dituju
 	dialect := mysql.Open("root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local")
+
+	// implementasi structured logger
+	// log query dalam bentuk json, dengan value kolom sensitif (password) di redact
+	sqlLogger, err := NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stdout, nil)), SlogLoggerConfig{
+		LogLevel:      logger.Info,
+		SlowThreshold: 200 * time.Millisecond,
+		Models:        []interface{}{&User{}},
+	})
+
+	// mengecek error
+	if err != nil {
+		panic(err)
+	}
+
+	// implementasi enkripsi kolom, keyring harus di atur sebelum model dengan serializer:encrypted digunakan
+	keyring, err := NewKeyring("test-1", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+	})
+
+	// mengecek error
+	if err != nil {
+		panic(err)
+	}
+
+	SetEncryptionKeyring(keyring)
+
 	db, err := gorm.Open(dialect, &gorm.Config{
 		// implementasi logger
 		// menambahkan logger untuk memunculkan informasi log query sql
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger: sqlLogger,
 
 		// implementasi performance
 		// tips 1 : matikan auto transaction
@@ -30,6 +81,9 @@ func OpenConnection() *gorm.DB {
 
 		// tips 2 : cache prepared statement
 		PrepareStmt: true,
+
+		// implementasi clock, waktu autoCreateTime dan autoUpdateTime diambil dari clock
+		NowFunc: SystemClock.Now,
 	})
 
 	// mengecek error
@@ -37,37 +91,34 @@ func OpenConnection() *gorm.DB {
 		panic(err)
 	}
 
-	// implementasi connection pool
-	sqlDB, err := db.DB()
+	// implementasi delete policy, relasi user ikut di proses ketika user di hapus (lihat delete_policy.go)
+	err = db.Use(NewDeletePolicyEnforcer())
+
+	// mengecek error
+	if err != nil {
+		panic(err)
+	}
+
+	// implementasi deteksi N+1 query, hanya aktif untuk query dengan QueryScope pada context (lihat n_plus_one.go)
+	err = db.Use(NewNPlusOneDetector(NPlusOneConfig{Threshold: 2}))
 
 	// mengecek error
 	if err != nil {
 		panic(err)
 	}
 
-	// mengatur connection pool
-	// Batas maksimal koneksi ke database yang boleh aktif bersamaan.
-	// Kalau sudah 100 koneksi dipakai:
-	// - request berikutnya akan menunggu
-	// - bukan bikin koneksi baru
-	sqlDB.SetMaxOpenConns(100)
-
-	// Jumlah koneksi yang disimpan dalam kondisi siap pakai (nganggur).
-	// Saat ada request baru:
-	// - ambil dari sini dulu
-	// - jadi lebih cepat daripada buat koneksi baru
-	sqlDB.SetMaxIdleConns(10)
-
-	// Umur maksimal sebuah koneksi.
-	// Setelah 30 menit:
-	// - koneksi dipensiunkan
-	// - diganti koneksi baru
-	sqlDB.SetConnMaxLifetime(30 * time.Minute)
-
-	// Batas waktu koneksi boleh menganggur.
-	// Kalau 5 menit tidak dipakai:
-	// - koneksi akan ditutup
-	sqlDB.SetConnMaxIdleTime(5 * time.Minute)
+	// implementasi connection pool
+	// pengaturan yang sama juga digunakan OpenConnectionWithRetry (lihat PoolConfig di health.go) :
+	// - maksimal 100 koneksi aktif, request berikutnya akan menunggu (bukan bikin koneksi baru)
+	// - 10 koneksi nganggur disimpan, request baru ambil dari sini dulu agar lebih cepat
+	// - setelah 30 menit koneksi dipensiunkan dan diganti koneksi baru
+	// - koneksi yang 5 menit tidak dipakai akan ditutup
+	err = ConfigurePool(db, DefaultPoolConfig())
+
+	// mengecek error
+	if err != nil {
+		panic(err)
+	}
 
 	return db
 }
@@ -624,7 +675,7 @@ func TestAutoIncrement(t *testing.T) {
 		// membuat data untuk user log
 		userLog := UserLog{
 			UserId: "1",
-			Action: "Test Action",
+			Action: ActionLogin,
 		}
 
 		// melakukan insert data ke database
@@ -634,7 +685,7 @@ func TestAutoIncrement(t *testing.T) {
 		assert.Nil(t, err)
 
 		// memastikan auto increment, dengan mengecek id dari setiap data yang berhasil di buat bukan 0
-		assert.NotEqual(t, 0, userLog.ID)
+		assert.NotEqual(t, int64(0), userLog.ID)
 		fmt.Println(userLog.ID) // menampilkan id yang berhasil dibuat ke database
 	}
 }
@@ -644,7 +695,7 @@ func TestSaveOrUpdate(t *testing.T) {
 	// membuat data struct user log untuk ditambahkan dan di ubah ke database
 	userLog := UserLog{
 		UserId: "1",
-		Action: "Action Test",
+		Action: ActionLogout,
 	}
 
 	// melakukan query dengan save
@@ -1455,12 +1506,13 @@ func TestContext(t *testing.T) {
 // dengan scopes memungkinkan kita untuk melakukan kustomisasi logic pada query database
 func BrokeWalletBalance(db *gorm.DB) *gorm.DB {
 	// di dalam function scopes, kita bisa tambahkan kustomisasi query yang kita inginkan
-	return db.Where("balance = ?", 0)
+	// menggunakan scopes dengan parameter dari package scopes
+	return db.Scopes(scopes.BalanceBetween(0, 0))
 }
 
 func SultanWalletBalance(db *gorm.DB) *gorm.DB {
 	// di dalam function scopes, kita bisa tambahkan kustomisasi query yang kita inginkan
-	return db.Where("balance >= ?", 1000000)
+	return db.Scopes(scopes.BalanceAtLeast(1000000))
 }
 
 func TestScopes(t *testing.T) {
@@ -1518,4 +1570,1919 @@ func TestHook(t *testing.T) {
 
 	// menampilkan id user
 	fmt.Println(user.ID)
-}
\ No newline at end of file
+}
+
+// implementasi role based access control
+func TestRoleBasedAccessControl(t *testing.T) {
+	// membuat tabel roles, permissions beserta tabel penghubung nya
+	err := db.Migrator().AutoMigrate(&Permission{}, &Role{}, &User{})
+	assert.Nil(t, err)
+
+	// nama role dan permission unique, data dihapus sebelum dan sesudah test agar test bisa dijalankan berulang kali
+	cleanup := func() {
+		roles := db.Model(&Role{}).Select("id").Where("name = ?", "admin")
+		db.Exec("DELETE FROM user_roles WHERE role_id IN (?)", roles)
+		db.Exec("DELETE FROM role_permissions WHERE role_id IN (?)", roles)
+		db.Where("name = ?", "admin").Delete(&Role{})
+		db.Where("name IN ?", []string{"todo:read", "todo:read:any"}).Delete(&Permission{})
+	}
+	cleanup()
+	defer cleanup()
+
+	// menyiapkan role admin yang boleh melihat semua todo
+	role := Role{
+		Name: "admin",
+		Permissions: []Permission{
+			{Name: "todo:read"},
+			{Name: "todo:read:any"},
+		},
+	}
+	err = db.Create(&role).Error
+	assert.Nil(t, err)
+
+	// memberikan role admin ke user 1
+	err = db.Model(&User{ID: "1"}).Association("Roles").Append(&role)
+	assert.Nil(t, err)
+
+	// membuat authorizer dengan cache selama 1 menit
+	authorizer := NewAuthorizer(db, time.Minute)
+	ctx := context.Background()
+
+	// user 1 memiliki permission todo:read:any
+	can, err := authorizer.Can(ctx, "1", "read:any", "todo")
+	assert.Nil(t, err)
+	assert.True(t, can)
+
+	// user 2 tidak memiliki role apapun
+	can, err = authorizer.Can(ctx, "2", "read:any", "todo")
+	assert.Nil(t, err)
+	assert.False(t, can)
+
+	// user 2 hanya bisa melihat todo milik nya sendiri
+	var todos []Todo
+	err = db.Scopes(authorizer.VisibleTodos(ctx, "2")).Find(&todos).Error
+	assert.Nil(t, err)
+	for _, todo := range todos {
+		assert.Equal(t, "2", todo.UserId)
+	}
+}
+
+// implementasi read/write splitting
+func TestResolver(t *testing.T) {
+	// membuka koneksi baru ke primary, agar plugin tidak terpasang pada koneksi global
+	primary := OpenConnection()
+
+	// replica menggunakan database yang sama, untuk pengujian di komputer lokal
+	replica := mysql.Open("root:@tcp(localhost:3306)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local")
+	err := primary.Use(NewResolver(RoundRobinPolicy(), replica))
+	assert.Nil(t, err)
+
+	// query read akan diarahkan ke replica
+	var user User
+	err = primary.Take(&user, "id = ?", "1").Error
+	assert.Nil(t, err)
+
+	// setelah write di dalam satu request, read berikutnya tetap ke primary (sticky)
+	ctx := WithStickyPrimary(context.Background())
+	err = primary.WithContext(ctx).Model(&user).Update("middle_name", "H").Error
+	assert.Nil(t, err)
+
+	err = primary.WithContext(ctx).Take(&user, "id = ?", "1").Error
+	assert.Nil(t, err)
+	assert.Equal(t, "H", user.Name.MiddleName)
+
+	// memaksa query ke primary menggunakan scopes
+	err = primary.Scopes(UsePrimary).Take(&user, "id = ?", "1").Error
+	assert.Nil(t, err)
+}
+
+// model untuk pengujian resolver dengan dua file sqlite
+type ResolverSample struct {
+	ID   int64  `gorm:"primary_key;column:id"`
+	Name string `gorm:"column:name"`
+}
+
+func (r ResolverSample) TableName() string {
+	return "resolver_samples"
+}
+
+// implementasi read/write splitting dengan primary dan replica berupa dua file sqlite berbeda,-
+// isi kedua database sengaja dibuat berbeda agar terlihat query diarahkan ke mana
+func TestResolverSQLite(t *testing.T) {
+	dir := t.TempDir()
+	primaryPath := dir + "/primary.db"
+	replicaPath := dir + "/replica.db"
+
+	for path, name := range map[string]string{primaryPath: "primary", replicaPath: "replica"} {
+		conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+		assert.Nil(t, err)
+		assert.Nil(t, conn.AutoMigrate(&ResolverSample{}))
+		assert.Nil(t, conn.Create(&ResolverSample{ID: 1, Name: name}).Error)
+		sqlDB, _ := conn.DB()
+		sqlDB.Close()
+	}
+
+	primary, err := gorm.Open(sqlite.Open(primaryPath), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	err = primary.Use(NewResolver(RoundRobinPolicy(), sqlite.Open(replicaPath)))
+	assert.Nil(t, err)
+
+	// membaca langsung dari masing masing file, tanpa resolver
+	names := func(path string) []string {
+		conn, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+		assert.Nil(t, err)
+		sqlDB, _ := conn.DB()
+		defer sqlDB.Close()
+
+		var names []string
+		assert.Nil(t, conn.Model(&ResolverSample{}).Order("id").Pluck("name", &names).Error)
+		return names
+	}
+
+	// read ke replica, kecuali dipaksa ke primary
+	var sample ResolverSample
+	assert.Nil(t, primary.Take(&sample, 1).Error)
+	assert.Equal(t, "replica", sample.Name)
+	assert.Nil(t, primary.Scopes(UsePrimary).Take(&sample, 1).Error)
+	assert.Equal(t, "primary", sample.Name)
+	assert.Nil(t, primary.WithContext(ForcePrimary(context.Background())).Take(&sample, 1).Error)
+	assert.Equal(t, "primary", sample.Name)
+
+	// write ke primary, setelah write read dengan context yang sama tetap ke primary (sticky)
+	ctx := WithStickyPrimary(context.Background())
+	assert.Nil(t, primary.WithContext(ctx).Create(&ResolverSample{ID: 2, Name: "created"}).Error)
+	assert.Equal(t, []string{"primary", "created"}, names(primaryPath))
+	assert.Equal(t, []string{"replica"}, names(replicaPath))
+	var samples []ResolverSample
+	assert.Nil(t, primary.WithContext(ctx).Order("id").Find(&samples).Error)
+	assert.Equal(t, 2, len(samples))
+
+	assert.Nil(t, primary.Model(&ResolverSample{}).Where("id = ?", 1).Update("name", "updated").Error)
+	assert.Equal(t, []string{"updated", "created"}, names(primaryPath))
+	assert.Equal(t, []string{"replica"}, names(replicaPath))
+
+	// statement yang dipakai ulang setelah read (replica), write tetap ke primary
+	tx := primary.Where("id = ?", 1)
+	assert.Nil(t, tx.Find(&samples).Error)
+	assert.Equal(t, "replica", samples[0].Name)
+	assert.Nil(t, tx.Delete(&ResolverSample{}).Error)
+	assert.Equal(t, []string{"created"}, names(primaryPath))
+	assert.Equal(t, []string{"replica"}, names(replicaPath))
+
+	assert.Nil(t, primary.Exec("UPDATE resolver_samples SET name = ? WHERE id = ?", "raw", 2).Error)
+	assert.Equal(t, []string{"raw"}, names(primaryPath))
+	assert.Equal(t, []string{"replica"}, names(replicaPath))
+
+	// query di dalam transaction selalu ke primary
+	var inTransaction ResolverSample
+	err = primary.Transaction(func(tx *gorm.DB) error {
+		return tx.Take(&inTransaction, 2).Error
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "raw", inTransaction.Name)
+}
+
+// implementasi health check dan statistik connection pool
+func TestHealth(t *testing.T) {
+	// membuat health checker dengan timeout ping 1 detik
+	checker := NewHealthChecker(db, time.Second)
+
+	report := checker.Health(context.Background())
+	assert.Equal(t, HealthStatusUp, report.Status)
+	assert.Equal(t, 100, report.Pool.MaxOpenConnections) // sesuai pengaturan di OpenConnection()
+
+	// menampilkan statistik dalam format prometheus text
+	var builder strings.Builder
+	err := report.WritePrometheus(&builder)
+	assert.Nil(t, err)
+	assert.Contains(t, builder.String(), "db_up 1")
+	fmt.Println(builder.String())
+}
+
+// implementasi koneksi dengan retry
+func TestOpenConnectionWithRetry(t *testing.T) {
+	// mencoba koneksi ke port yang salah, dibatasi 3 kali percobaan
+	dialect := mysql.Open("root:@tcp(localhost:3307)/belajar_golang_gorm?charset=utf8mb4&parseTime=True&loc=Local")
+	_, err := OpenConnectionWithRetry(context.Background(), dialect, &gorm.Config{}, ConnectRetry{
+		MaxAttempts:  3,
+		InitialDelay: 10 * time.Millisecond,
+		MaxDelay:     50 * time.Millisecond,
+	})
+
+	// tidak panic, namun mengembalikan error
+	assert.NotNil(t, err)
+}
+
+// dialector yang menghitung jumlah percobaan koneksi
+type countingDialector struct {
+	gorm.Dialector
+	attempts int
+}
+
+func (d *countingDialector) Initialize(db *gorm.DB) error {
+	d.attempts++
+	return d.Dialector.Initialize(db)
+}
+
+// koneksi dengan retry menggunakan pengaturan connection pool yang sama dengan OpenConnection()
+// tanpa InitialDelay, retry tetap memiliki jeda (tidak menjadi loop tanpa jeda)
+func TestOpenConnectionWithRetryDefaults(t *testing.T) {
+	conn, err := OpenConnectionWithRetry(context.Background(), sqlite.Open(t.TempDir()+"/retry.db"), &gorm.Config{Logger: logger.Discard}, ConnectRetry{})
+	assert.Nil(t, err)
+	sqlDB, err := conn.DB()
+	assert.Nil(t, err)
+	defer sqlDB.Close()
+	assert.Equal(t, 100, sqlDB.Stats().MaxOpenConnections)
+
+	// file sqlite di folder yang tidak ada, koneksi selalu gagal
+	dialector := &countingDialector{Dialector: sqlite.Open(t.TempDir() + "/missing/retry.db")}
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	_, err = OpenConnectionWithRetry(ctx, dialector, &gorm.Config{Logger: logger.Discard}, ConnectRetry{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.LessOrEqual(t, dialector.attempts, 4)
+}
+
+// implementasi structured logger dengan redaction
+func TestSlogLoggerRedaction(t *testing.T) {
+	// menampung output log di memory
+	var output strings.Builder
+	sqlLogger, err := NewSlogLogger(slog.New(slog.NewJSONHandler(&output, nil)), SlogLoggerConfig{
+		LogLevel:      logger.Info,
+		SlowThreshold: time.Second,
+		Models:        []interface{}{&User{}},
+	})
+	assert.Nil(t, err)
+
+	// menggunakan session baru dengan logger yang sudah dibuat
+	session := db.Session(&gorm.Session{Logger: sqlLogger})
+	ctx := ContextWithRequestID(context.Background(), "request-1")
+
+	var user User
+	err = session.WithContext(ctx).Where("password = ?", "rahasia").Take(&user).Error
+	assert.Nil(t, err)
+
+	// password tidak boleh muncul di log
+	assert.NotContains(t, output.String(), "rahasia")
+	assert.Contains(t, output.String(), "[REDACTED]")
+	assert.Contains(t, output.String(), `"request_id":"request-1"`)
+}
+
+// semua value di dalam daftar IN (?, ?) milik kolom sensitif ikut di redact
+func TestSlogLoggerRedactionList(t *testing.T) {
+	sqlLogger, err := NewSlogLogger(slog.New(slog.NewJSONHandler(io.Discard, nil)), SlogLoggerConfig{
+		LogLevel: logger.Info,
+		Models:   []interface{}{&User{}},
+	})
+	assert.Nil(t, err)
+
+	ctx := context.Background()
+	_, params := sqlLogger.ParamsFilter(ctx, "SELECT * FROM `users` WHERE `users`.`password` IN (?,?,?) AND id NOT IN (?, ?)",
+		"rahasia-1", "rahasia-2", "rahasia-3", "1", "2")
+	assert.Equal(t, []interface{}{redacted, redacted, redacted, "1", "2"}, params)
+
+	_, params = sqlLogger.ParamsFilter(ctx, "SELECT * FROM users WHERE id = $1 AND password NOT IN ($2, $3)", "1", "rahasia-1", "rahasia-2")
+	assert.Equal(t, []interface{}{"1", redacted, redacted}, params)
+
+	_, params = sqlLogger.ParamsFilter(ctx, "INSERT INTO `users` (`id`,`password`) VALUES (?,?),(?,?)", "1", "rahasia-1", "2", "rahasia-2")
+	assert.Equal(t, []interface{}{"1", redacted, "2", redacted}, params)
+}
+
+// implementasi plugin tracing dan metrics
+func TestTracer(t *testing.T) {
+	// membuka koneksi baru, agar plugin tidak terpasang pada koneksi global
+	tracedDB := OpenConnection()
+
+	// menggunakan in memory exporter untuk pengujian
+	exporter := NewInMemoryExporter()
+	err := tracedDB.Use(NewTracer(exporter))
+	assert.Nil(t, err)
+
+	var users []User
+	err = tracedDB.Where("id IN ?", []string{"1", "2", "3"}).Find(&users).Error
+	assert.Nil(t, err)
+
+	// setiap query tercatat sebagai span, dengan fingerprint tanpa literal
+	spans := exporter.Spans()
+	assert.Equal(t, 1, len(spans))
+	assert.Equal(t, "users", spans[0].Table)
+	assert.Equal(t, "query", spans[0].Operation)
+	assert.Equal(t, "SELECT * FROM `users` WHERE id IN (?)", spans[0].Fingerprint)
+
+	histogram, ok := exporter.Histogram("users", "query")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), histogram.Count)
+}
+
+// implementasi retry transaction ketika deadlock
+func TestRetryTransaction(t *testing.T) {
+	metrics := NewRetryMetrics()
+	attempt := 0
+
+	err := RetryTransaction(context.Background(), db, RetryOptions{MaxAttempts: 3, Metrics: metrics}, func(tx *gorm.DB) error {
+		attempt++
+
+		// simulasi deadlock pada percobaan pertama
+		if attempt == 1 {
+			return &mysqlDriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+		}
+
+		return tx.Model(&User{}).Where("id = ?", "1").Update("middle_name", "R").Error
+	})
+
+	// transaction berhasil pada percobaan kedua
+	assert.Nil(t, err)
+	assert.Equal(t, 2, attempt)
+	assert.Equal(t, uint64(1), metrics.Retries(RetryReasonDeadlock))
+
+	// error yang tidak bisa di retry dikembalikan apa adanya
+	notRetryable := errors.New("saldo tidak cukup")
+	err = RetryTransaction(context.Background(), db, RetryOptions{Metrics: metrics}, func(tx *gorm.DB) error {
+		return notRetryable
+	})
+	assert.Equal(t, notRetryable, err)
+}
+
+// RetryTransaction di dalam transaction lain tidak melakukan retry, error diteruskan ke transaction luar
+func TestRetryTransactionNested(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/retry.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+
+	deadlock := &mysqlDriver.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	metrics := NewRetryMetrics()
+	options := RetryOptions{MaxAttempts: 3, InitialDelay: time.Millisecond, Metrics: metrics}
+
+	attempt := 0
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		return RetryTransaction(context.Background(), tx, options, func(tx *gorm.DB) error {
+			attempt++
+			return deadlock
+		})
+	})
+	assert.ErrorIs(t, err, deadlock)
+	assert.Equal(t, 1, attempt)
+	assert.Equal(t, uint64(0), metrics.Retries(RetryReasonDeadlock))
+
+	// transaction paling luar tetap di retry
+	attempt = 0
+	err = RetryTransaction(context.Background(), conn, options, func(tx *gorm.DB) error {
+		attempt++
+		return deadlock
+	})
+	assert.ErrorIs(t, err, deadlock)
+	assert.Equal(t, 3, attempt)
+	assert.Equal(t, uint64(2), metrics.Retries(RetryReasonDeadlock))
+}
+
+// implementasi transactional outbox
+func TestOutbox(t *testing.T) {
+	// membuat tabel outbox_events
+	err := db.Migrator().AutoMigrate(&OutboxEvent{})
+	assert.Nil(t, err)
+
+	// data dari test sebelumnya dihapus agar test bisa dijalankan berulang kali
+	cleanup := func() {
+		db.Exec("DELETE FROM wallets WHERE id = ?", "outbox-wallet-1")
+		db.Exec("DELETE FROM users WHERE id = ?", "outbox-1")
+	}
+	cleanup()
+	defer cleanup()
+
+	// membuat user baru, event user.created di simpan dalam transaction yang sama
+	user := User{ID: "outbox-1", Password: "rahasia", Name: Name{FirstName: "User Outbox"}}
+	err = CreateUserWithEvent(context.Background(), db, &user)
+	assert.Nil(t, err)
+
+	wallet := Wallet{ID: "outbox-wallet-1", UserId: user.ID, Balance: 100000}
+	err = db.Create(&wallet).Error
+	assert.Nil(t, err)
+
+	// debit wallet yang balance nya tidak cukup akan di rollback, event tidak tercatat
+	err = DebitWallet(context.Background(), db, wallet.ID, 1_000_000_000)
+	assert.Equal(t, ErrInsufficientBalance, err)
+
+	err = DebitWallet(context.Background(), db, wallet.ID, 40000)
+	assert.Nil(t, err)
+	err = db.Take(&wallet, "id = ?", wallet.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60000), wallet.Balance)
+
+	// relay mengirim event ke publisher
+	var published []OutboxEvent
+	relay := NewOutboxRelay(db, PublisherFunc(func(ctx context.Context, event OutboxEvent) error {
+		published = append(published, event)
+		return nil
+	}), 10, time.Second)
+
+	count, err := relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, count >= 2)
+	types := map[string]string{}
+	for _, event := range published {
+		types[event.AggregateID] = event.EventType
+	}
+	assert.Equal(t, EventUserCreated, types["outbox-1"])
+	assert.Equal(t, EventWalletDebited, types["outbox-wallet-1"])
+
+	// event yang sudah terkirim tidak akan dikirim lagi
+	count, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// relay mengirim beberapa event dari aggregate yang sama dalam satu polling dengan urutan yang terjaga
+func TestOutboxRelayOrdering(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/outbox.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.AutoMigrate(&OutboxEvent{}))
+
+	for i := 1; i <= 3; i++ {
+		assert.Nil(t, RecordEvent(conn, "wallet", "w1", EventWalletDebited, map[string]int{"amount": i}))
+		assert.Nil(t, RecordEvent(conn, "wallet", "w2", EventWalletDebited, map[string]int{"amount": i}))
+	}
+
+	// event kedua milik w1 gagal dikirim, event ketiga w1 tidak boleh dikirim mendahului nya
+	var published []string
+	failing := true
+	relay := NewOutboxRelay(conn, PublisherFunc(func(ctx context.Context, event OutboxEvent) error {
+		if failing && event.AggregateID == "w1" && event.Payload == `{"amount":2}` {
+			return errors.New("broker unavailable")
+		}
+		published = append(published, event.AggregateID+" "+event.Payload)
+		return nil
+	}), 10, time.Second)
+
+	count, err := relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, []string{
+		`w1 {"amount":1}`, `w2 {"amount":1}`, `w2 {"amount":2}`, `w2 {"amount":3}`,
+	}, published)
+
+	// event yang di klaim relay lain (belum kadaluarsa) dilewati beserta event setelah nya
+	claimedUntil := time.Now().Add(time.Minute)
+	assert.Nil(t, conn.Model(&OutboxEvent{}).Where("aggregate_id = ? AND payload = ?", "w1", `{"amount":2}`).
+		Update("claimed_until", &claimedUntil).Error)
+	failing = false
+	published = nil
+	count, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	// setelah klaim dilepas, sisa event w1 dikirim berurutan dalam satu polling
+	assert.Nil(t, conn.Model(&OutboxEvent{}).Where("aggregate_id = ?", "w1").Update("claimed_until", nil).Error)
+	count, err = relay.RelayOnce(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, []string{`w1 {"amount":2}`, `w1 {"amount":3}`}, published)
+
+	var event OutboxEvent
+	assert.Nil(t, conn.Where("aggregate_id = ? AND payload = ?", "w1", `{"amount":2}`).Take(&event).Error)
+	assert.Equal(t, 2, event.Attempts)
+	assert.Equal(t, "broker unavailable", event.LastError)
+	assert.Nil(t, event.ClaimedUntil)
+	assert.NotNil(t, event.PublishedAt)
+}
+
+// implementasi bulk import csv
+func TestImportUsers(t *testing.T) {
+	// kolom nama_depan di petakan ke field embedded Name.FirstName
+	file := strings.NewReader("id,password,nama_depan,last_name\n" +
+		"import-1,rahasia,Import,Satu\n" +
+		"import-2,,Import,Dua\n" + // ditolak karena password kosong
+		"import-3,rahasia,Import,Tiga\n")
+
+	var rejects strings.Builder
+	result, err := ImportUsers(context.Background(), db, file, ImportOptions{
+		Format:       ImportCSV,
+		Mapping:      map[string]string{"nama_depan": "Name.FirstName"},
+		BatchSize:    100,
+		RejectWriter: &rejects,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, result.Processed)
+	assert.Equal(t, 2, result.Imported)
+	assert.Equal(t, 1, result.Rejected)
+	assert.Contains(t, rejects.String(), "password is required")
+
+	// memastikan data embedded ter isi
+	var user User
+	err = db.Take(&user, "id = ?", "import-1").Error
+	assert.Nil(t, err)
+	assert.Equal(t, "Import", user.Name.FirstName)
+	assert.Equal(t, "Satu", user.Name.LastName)
+}
+
+// implementasi bulk import ndjson dengan dry run
+func TestImportProductsDryRun(t *testing.T) {
+	file := strings.NewReader(`{"id": "P100", "name": "Product 100", "price": 10000}` + "\n" +
+		`{"id": "P101", "name": "Product 101", "price": -1}` + "\n")
+
+	result, err := ImportProducts(context.Background(), db, file, ImportOptions{Format: ImportNDJSON, DryRun: true})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, 1, result.Rejected)
+
+	// dry run tidak menyimpan data ke database
+	var count int64
+	err = db.Model(&Product{}).Where("id = ?", "P100").Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+// model untuk pengujian checkpoint import
+type ImportSample struct {
+	ID   int64  `gorm:"primary_key;column:id"`
+	Name string `gorm:"column:name"`
+}
+
+func (s ImportSample) TableName() string {
+	return "import_samples"
+}
+
+func (s *ImportSample) Validate() error {
+	if s.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// reader yang gagal setelah isi nya habis, simulasi import yang terhenti di tengah file
+type failingReader struct {
+	io.Reader
+}
+
+func (r failingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+// import yang terhenti dilanjutkan dari checkpoint, nomor baris adalah baris fisik pada file
+func TestImportResume(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/import.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.AutoMigrate(&ImportSample{}))
+
+	lines := []string{
+		"id,name", // baris 1
+		"1,A",     // baris 2
+		"2,\"B",   // baris 3, value dengan newline
+		"multi\"", // baris 4
+		"3,",      // baris 5, ditolak
+		"4,D",     // baris 6
+		"5,E",     // baris 7
+		"6,",      // baris 8, ditolak setelah batch terakhir
+	}
+	file := strings.Join(lines, "\n") + "\n"
+	var rejects strings.Builder
+	opts := ImportOptions{Format: ImportCSV, BatchSize: 2, RejectWriter: &rejects, CheckpointPath: t.TempDir() + "/import.checkpoint"}
+
+	// terhenti setelah baris 6, hanya batch pertama (baris 2 - 3) yang tersimpan
+	result, err := Import[ImportSample](context.Background(), conn, failingReader{strings.NewReader(strings.Join(lines[:6], "\n") + "\n")}, opts)
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, result.Imported)
+	assert.Contains(t, rejects.String(), "5,name is required")
+
+	// dilanjutkan dari checkpoint, baris 2 - 3 dilewati
+	rejects.Reset()
+	result, err = Import[ImportSample](context.Background(), conn, strings.NewReader(file), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{Processed: 4, Imported: 2, Rejected: 2, Skipped: 2}, result)
+	assert.Equal(t, "line,error,record\n5,name is required,\"3,\"\n8,name is required,\"6,\"\n", rejects.String())
+
+	// semua baris sudah diproses, termasuk baris yang ditolak setelah batch terakhir
+	rejects.Reset()
+	result, err = Import[ImportSample](context.Background(), conn, strings.NewReader(file), opts)
+	assert.Nil(t, err)
+	assert.Equal(t, ImportResult{Skipped: 6}, result)
+	assert.Equal(t, "line,error,record\n", rejects.String())
+
+	var names []string
+	assert.Nil(t, conn.Model(&ImportSample{}).Order("id").Pluck("name", &names).Error)
+	assert.Equal(t, []string{"A", "B\nmulti", "D", "E"}, names)
+}
+
+// implementasi streaming export
+func TestExportUsers(t *testing.T) {
+	// kolom embedded dan relasi di ratakan menjadi kolom biasa
+	columns := []ExportColumn{
+		{Header: "ID", Path: "ID"},
+		{Header: "Nama Depan", Path: "Name.FirstName"},
+		{Header: "Saldo", Path: "Wallet.Balance"},
+		{Header: "Alamat", Path: "Addresses.Address"},
+	}
+
+	var output strings.Builder
+	query := db.Model(&User{}).Preload("Wallet").Preload("Addresses").Where("id IN ?", []string{"1", "50"})
+
+	total, err := Export[User](context.Background(), query, &output, ExportOptions{
+		Format:    ExportCSV,
+		Columns:   columns,
+		BatchSize: 100,
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, total)
+	assert.Contains(t, output.String(), "ID,Nama Depan,Saldo,Alamat")
+	assert.Contains(t, output.String(), "Indonesia; Banyuwangi")
+	fmt.Println(output.String())
+}
+
+// implementasi retention user_logs
+func TestUserLogRetention(t *testing.T) {
+	// menambahkan user log lama (100 hari yang lalu)
+	oldLog := UserLog{
+		UserId:    "1",
+		Action:    ActionLogout,
+		CreatedAt: time.Now().AddDate(0, 0, -100).UnixMilli(),
+		UpdatedAt: time.Now().AddDate(0, 0, -100).UnixMilli(),
+	}
+	err := db.Create(&oldLog).Error
+	assert.Nil(t, err)
+
+	// archive user log yang lebih lama dari 90 hari
+	retention := NewUserLogRetention(db, RetentionPolicy{Days: 90, ArchiveDir: t.TempDir(), BatchSize: 100})
+	result, err := retention.Run(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, result.Archived >= 1)
+
+	// data sudah tidak ada di database
+	var count int64
+	err = db.Model(&UserLog{}).Where("created_at < ?", retention.Cutoff()).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// mengembalikan file archive ke database
+	restored := 0
+	for _, file := range result.Files {
+		total, err := RestoreUserLogArchive(context.Background(), db, file)
+		assert.Nil(t, err)
+		restored += total
+	}
+	assert.Equal(t, result.Archived, restored)
+}
+
+// implementasi action user log dan payload json
+func TestUserActivityTimeline(t *testing.T) {
+	// menyesuaikan tabel user_logs lama (id string menjadi integer, menambahkan kolom payload)
+	err := MigrateUserLogs(context.Background(), db)
+	assert.Nil(t, err)
+
+	// membuat user log dengan payload sesuai jenis action nya
+	login, err := NewUserLog("timeline-1", ActionLogin, LoginPayload{IP: "127.0.0.1", UserAgent: "Go Test"})
+	assert.Nil(t, err)
+	err = db.Create(&login).Error
+	assert.Nil(t, err)
+
+	debit, err := NewUserLog("timeline-1", ActionWalletDebit, WalletPayload{WalletID: "1", Amount: 1000, Balance: 9000})
+	assert.Nil(t, err)
+	err = db.Create(&debit).Error
+	assert.Nil(t, err)
+
+	// payload yang tidak sesuai dengan action akan ditolak
+	_, err = NewUserLog("timeline-1", ActionLogin, WalletPayload{})
+	assert.NotNil(t, err)
+
+	// action yang belum terdaftar juga akan ditolak oleh hook BeforeCreate
+	err = db.Create(&UserLog{UserId: "timeline-1", Action: "unknown"}).Error
+	assert.NotNil(t, err)
+
+	// mengambil timeline khusus action wallet dalam 1 jam terakhir
+	logs, err := UserActivityTimeline(context.Background(), db, "timeline-1", ActivityFilter{
+		Actions: []UserAction{ActionWalletDebit, ActionWalletCredit},
+		From:    time.Now().Add(-time.Hour),
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(logs))
+
+	payload, err := logs[0].DecodePayload()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1000), payload.(WalletPayload).Amount)
+}
+
+// migrasi dan restore archive user_logs dari format lama (id string dan action teks bebas)
+func TestUserLogLegacyRestore(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/user_logs.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+
+	// tabel user_logs lama
+	err = conn.Exec("CREATE TABLE user_logs (id varchar(100) PRIMARY KEY, user_id varchar(100), action varchar(100), " +
+		"created_at bigint, updated_at bigint)").Error
+	assert.Nil(t, err)
+	err = conn.Exec("INSERT INTO user_logs VALUES ('1', 'legacy-1', 'login', 1, 1), ('abc', 'legacy-1', 'Membuka halaman profile', 2, 2)").Error
+	assert.Nil(t, err)
+
+	err = MigrateUserLogs(context.Background(), conn)
+	assert.Nil(t, err)
+
+	var logs []UserLog
+	err = conn.Order("id").Find(&logs).Error
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(logs))
+	assert.Equal(t, ActionLogin, logs[0].Action)
+	assert.Equal(t, int64(2), logs[1].ID)
+	assert.Equal(t, ActionLegacy, logs[1].Action)
+
+	payload, err := logs[1].DecodePayload()
+	assert.Nil(t, err)
+	assert.Equal(t, LegacyPayload{Action: "Membuka halaman profile"}, payload)
+
+	// action yang sudah di migrasi bisa di update tanpa ditolak hook
+	err = conn.Model(&logs[1]).Update("user_id", "legacy-2").Error
+	assert.Nil(t, err)
+
+	// archive lama, id masih string dan action teks bebas
+	path := t.TempDir() + "/user_logs_old.ndjson.gz"
+	file, err := os.Create(path)
+	assert.Nil(t, err)
+	writer := gzip.NewWriter(file)
+	_, err = writer.Write([]byte(`{"ID":"10","UserId":"legacy-1","Action":"Menghapus alamat","CreatedAt":3,"UpdatedAt":3}` + "\n" +
+		`{"ID":"11","UserId":"legacy-1","Action":"logout","CreatedAt":4,"UpdatedAt":4}` + "\n" +
+		`{"ID":"xyz","UserId":"legacy-1","Action":"logout","CreatedAt":5,"UpdatedAt":5}` + "\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, writer.Close())
+	assert.Nil(t, file.Close())
+
+	restored, err := RestoreUserLogArchive(context.Background(), conn, path)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, restored)
+
+	var restoredLog UserLog
+	err = conn.Take(&restoredLog, "id = ?", 10).Error
+	assert.Nil(t, err)
+	assert.Equal(t, ActionLegacy, restoredLog.Action)
+	assert.Equal(t, int64(3), restoredLog.CreatedAt)
+
+	payload, err = restoredLog.DecodePayload()
+	assert.Nil(t, err)
+	assert.Equal(t, LegacyPayload{Action: "Menghapus alamat"}, payload)
+
+	// archive format baru tetap bisa di restore, dan restore ulang tidak menambah data
+	var logout UserLog
+	err = conn.Take(&logout, "id = ?", 11).Error
+	assert.Nil(t, err)
+	assert.Equal(t, ActionLogout, logout.Action)
+
+	retention := NewUserLogRetention(conn, RetentionPolicy{Days: 1, ArchiveDir: t.TempDir()})
+	result, err := retention.Run(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 5, result.Archived)
+
+	for _, archive := range result.Files {
+		restored, err = RestoreUserLogArchive(context.Background(), conn, archive)
+		assert.Nil(t, err)
+		assert.Equal(t, 5, restored)
+
+		restored, err = RestoreUserLogArchive(context.Background(), conn, archive)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, restored)
+	}
+}
+
+// implementasi fake clock untuk timestamp dan hook
+func TestFakeClock(t *testing.T) {
+	clock := NewFakeClock(time.Date(2025, time.January, 1, 10, 0, 0, 0, time.Local))
+	tx := WithClock(db, clock)
+
+	// id user dari hook BeforeCreate juga menggunakan clock
+	user := User{
+		Password: "rahasia",
+		Name:     Name{FirstName: "User Clock"},
+	}
+	err := tx.Create(&user).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "user-20250101100000", user.ID)
+	assert.True(t, clock.Now().Equal(user.CreatedAt))
+	assert.True(t, clock.Now().Equal(user.UpdatedAt))
+
+	// timestamp mode milli pada user log
+	userLog := UserLog{UserId: user.ID, Action: ActionLogin}
+	err = tx.Create(&userLog).Error
+	assert.Nil(t, err)
+	assert.Equal(t, clock.Now().UnixMilli(), userLog.CreatedAt)
+
+	// setelah clock di majukan, hanya updated_at yang berubah
+	updatedAt := clock.Advance(time.Hour)
+	err = tx.Model(&user).Update("middle_name", "Diupdate").Error
+	assert.Nil(t, err)
+
+	var result User
+	err = db.Take(&result, "id = ?", user.ID).Error
+	assert.Nil(t, err)
+	assert.True(t, updatedAt.Add(-time.Hour).Equal(result.CreatedAt))
+	assert.True(t, updatedAt.Equal(result.UpdatedAt))
+
+	err = db.Delete(&UserLog{}, "id = ?", userLog.ID).Error
+	assert.Nil(t, err)
+	err = db.Delete(&User{}, "id = ?", user.ID).Error
+	assert.Nil(t, err)
+}
+
+// implementasi enkripsi kolom address dan rotasi key
+func TestEncryptedAddress(t *testing.T) {
+	address := Address{UserId: "1", Address: "Jalan Rahasia No. 1"}
+	err := db.Create(&address).Error
+	assert.Nil(t, err)
+
+	// di database, value kolom address sudah dalam bentuk ciphertext
+	var raw string
+	err = db.Raw("SELECT address FROM addresses WHERE id = ?", address.ID).Scan(&raw).Error
+	assert.Nil(t, err)
+	keyID, encrypted := EncryptedKeyID(raw)
+	assert.True(t, encrypted)
+	assert.Equal(t, "test-1", keyID)
+
+	// ketika di baca menggunakan model, value otomatis di dekripsi
+	var result Address
+	err = db.Take(&result, "id = ?", address.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalan Rahasia No. 1", result.Address)
+
+	// rotasi key, key lama tetap ada di keyring agar data lama masih bisa di baca
+	oldKeyring := encryptionKeyring.Load()
+	keyring, err := NewKeyring("test-2", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+		"test-2": []byte("fedcba9876543210fedcba9876543210"),
+	})
+	assert.Nil(t, err)
+	SetEncryptionKeyring(keyring)
+	defer SetEncryptionKeyring(oldKeyring)
+
+	rotated, err := NewKeyRotationJob(db, 100, time.Minute, &Address{}).RotateOnce(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, rotated > 0)
+
+	err = db.Raw("SELECT address FROM addresses WHERE id = ?", address.ID).Scan(&raw).Error
+	assert.Nil(t, err)
+	keyID, _ = EncryptedKeyID(raw)
+	assert.Equal(t, "test-2", keyID)
+
+	err = db.Take(&result, "id = ?", address.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "Jalan Rahasia No. 1", result.Address)
+
+	err = db.Delete(&Address{}, "id = ?", address.ID).Error
+	assert.Nil(t, err)
+}
+
+// nonce deterministic tidak dibuat dengan key AES, melainkan dengan nonce key turunan HKDF
+func TestEncryptedDeterministicNonce(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	keyring, err := NewKeyring("test-1", map[string][]byte{"test-1": key})
+	assert.Nil(t, err)
+
+	first, err := keyring.Encrypt([]byte("eko@example.com"), true)
+	assert.Nil(t, err)
+	second, err := keyring.Encrypt([]byte("eko@example.com"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	plaintext, err := keyring.Decrypt(first)
+	assert.Nil(t, err)
+	assert.Equal(t, "eko@example.com", string(plaintext))
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(first, "enc:test-1:"))
+	assert.Nil(t, err)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("nonce"))
+	mac.Write([]byte("eko@example.com"))
+	assert.NotEqual(t, mac.Sum(nil)[:12], sealed[:12])
+}
+
+// implementasi scopes dengan parameter dan registry scopes
+func TestScopeLibrary(t *testing.T) {
+	var wallets []Wallet
+	err := db.Scopes(scopes.BalanceBetween(0, 1000000), scopes.OrderBy("balance", "desc")).Find(&wallets).Error
+	assert.Nil(t, err)
+	for i := 1; i < len(wallets); i++ {
+		assert.True(t, wallets[i-1].Balance >= wallets[i].Balance)
+	}
+
+	var users []User
+	err = db.Scopes(scopes.WithName("Eko"), scopes.CreatedBetween(time.Time{}, time.Now())).Find(&users).Error
+	assert.Nil(t, err)
+
+	// scope yang kolom nya tidak ada pada tabel akan ditolak sebelum query dijalankan
+	err = db.Scopes(scopes.Active()).Find(&wallets).Error
+	assert.True(t, errors.Is(err, scopes.ErrUnknownColumn))
+
+	err = db.Scopes(scopes.OrderBy("password; DROP TABLE users", "asc")).Find(&users).Error
+	assert.True(t, errors.Is(err, scopes.ErrUnknownColumn))
+
+	// scopes dari parameter request
+	request := httptest.NewRequest(http.MethodGet, "/wallets?balance_between=1000000,&order_by=balance:desc&page=1", nil)
+	query, err := scopes.DefaultRegistry().Apply(db.Model(&Wallet{}), request)
+	assert.Nil(t, err)
+
+	wallets = []Wallet{}
+	err = query.Find(&wallets).Error
+	assert.Nil(t, err)
+	for _, wallet := range wallets {
+		assert.True(t, wallet.Balance >= 1000000)
+	}
+
+	request = httptest.NewRequest(http.MethodGet, "/wallets?balance_between=banyak", nil)
+	_, err = scopes.DefaultRegistry().Apply(db.Model(&Wallet{}), request)
+	assert.NotNil(t, err)
+}
+
+// karakter wildcard pada pencarian nama dicari sebagai karakter biasa (LIKE ... ESCAPE)
+func TestScopeWithNameWildcard(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/scopes.db"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&GuestBook{}))
+
+	err = conn.Create(&[]GuestBook{{Name: "a_b"}, {Name: "axb"}, {Name: "50%"}, {Name: "500"}, {Name: `c\d`}}).Error
+	require.NoError(t, err)
+
+	for q, expected := range map[string]int64{"a_b": 1, "50%": 1, `c\d`: 1, "a": 2} {
+		var count int64
+		err = conn.Model(&GuestBook{}).Scopes(scopes.WithName(q)).Count(&count).Error
+		assert.Nil(t, err)
+		assert.Equal(t, expected, count, q)
+	}
+}
+
+// implementasi generic repository
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserRepository(db)
+
+	user, err := users.Get(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", user.ID)
+
+	_, err = users.Get(ctx, "tidak-ada")
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	user, err = users.WithWalletAndAddresses(ctx, "50")
+	assert.Nil(t, err)
+	assert.Equal(t, "50", user.Wallet.UserId)
+
+	exists, err := users.Exists(ctx, "1")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	list, err := users.List(ctx, ListOptions{Limit: 2, OrderBy: "id"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(list))
+
+	// update dengan field mask, hanya middle_name yang di ubah
+	err = users.Update(ctx, "1", User{Name: Name{MiddleName: "Repository", FirstName: "Tidak Diubah"}}, "middle_name")
+	assert.Nil(t, err)
+
+	user, err = users.Get(ctx, "1")
+	assert.Nil(t, err)
+	assert.Equal(t, "Repository", user.Name.MiddleName)
+	assert.NotEqual(t, "Tidak Diubah", user.Name.FirstName)
+
+	// update data yang tidak ada
+	err = users.Update(ctx, "tidak-ada", User{Name: Name{MiddleName: "Repository"}}, "middle_name")
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	// update dengan value yang sama tetap berhasil
+	err = users.Update(ctx, "1", User{Name: Name{MiddleName: "Repository"}}, "middle_name")
+	assert.Nil(t, err)
+
+	// lock wallet di dalam transaction, Get tidak mengambil relasi sehingga wallet di ambil dengan WithWalletAndAddresses
+	user, err = users.WithWalletAndAddresses(ctx, "50")
+	assert.Nil(t, err)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		wallet, err := NewWalletRepository(tx).ForUpdate(ctx, user.Wallet.ID)
+		if err != nil {
+			return err
+		}
+		assert.Equal(t, "50", wallet.UserId)
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+// implementasi repository in-memory untuk unit test tanpa database
+func TestMemoryRepository(t *testing.T) {
+	ctx := context.Background()
+
+	var wallets WalletRepository = NewMemoryWalletRepository()
+	err := wallets.Create(ctx, &Wallet{ID: "w1", UserId: "1", Balance: 1000})
+	assert.Nil(t, err)
+	err = wallets.Create(ctx, &Wallet{ID: "w1", UserId: "1"})
+	assert.True(t, errors.Is(err, gorm.ErrDuplicatedKey))
+
+	err = wallets.Update(ctx, "w1", Wallet{Balance: 0}, "balance")
+	assert.Nil(t, err)
+
+	wallet, err := wallets.ForUpdate(ctx, "w1")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), wallet.Balance)
+	assert.Equal(t, "1", wallet.UserId)
+
+	todos := NewMemoryRepository[Todo, uint]()
+	for _, title := range []string{"B", "A", "C"} {
+		err = todos.Create(ctx, &Todo{Title: title})
+		assert.Nil(t, err)
+	}
+
+	list, err := todos.List(ctx, ListOptions{OrderBy: "title", Limit: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, "A", list[0].Title)
+	assert.Equal(t, uint(2), list[0].ID)
+
+	err = todos.Delete(ctx, 10)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+
+	count, err := todos.Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), count)
+
+	// Todo menggunakan soft delete, data yang sudah di hapus tidak bisa dibaca dan id nya tidak bisa dipakai ulang
+	err = todos.Delete(ctx, 1)
+	assert.Nil(t, err)
+	_, err = todos.Get(ctx, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	err = todos.Update(ctx, 1, Todo{Title: "D"})
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	err = todos.Delete(ctx, 1)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	err = todos.Create(ctx, &Todo{Model: gorm.Model{ID: 1}, Title: "D"})
+	assert.True(t, errors.Is(err, gorm.ErrDuplicatedKey))
+
+	count, err = todos.Count(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// hook BeforeCreate pada User juga dijalankan, id di isi dari NowFunc
+	users := NewMemoryUserRepository()
+	users.NowFunc = func() time.Time { return time.Date(2025, time.January, 1, 10, 0, 0, 0, time.Local) }
+	user := User{Name: Name{FirstName: "Memory"}}
+	err = users.Create(ctx, &user)
+	assert.Nil(t, err)
+	assert.Equal(t, "user-20250101100000", user.ID)
+
+	user, err = users.Get(ctx, "user-20250101100000")
+	assert.Nil(t, err)
+	assert.Equal(t, "Memory", user.Name.FirstName)
+}
+
+// implementasi unit of work dengan nested transaction (savepoint) dan after commit callback
+func TestUnitOfWorkNested(t *testing.T) {
+	var committed []string
+
+	err := RunUnitOfWork(context.Background(), db, func(uow *UnitOfWork) error {
+		err := uow.DB().Create(&User{ID: "uow-1", Password: "rahasia", Name: Name{FirstName: "User UoW 1"}}).Error
+		if err != nil {
+			return err
+		}
+		uow.AfterCommit(func() { committed = append(committed, "uow-1") })
+
+		// nested gagal karena id duplikat, hanya bagian ini yang di rollback
+		err = uow.Nested(func(inner *UnitOfWork) error {
+			err := inner.DB().Create(&User{ID: "uow-2", Password: "rahasia", Name: Name{FirstName: "User UoW 2"}}).Error
+			if err != nil {
+				return err
+			}
+			inner.AfterCommit(func() { committed = append(committed, "uow-2") })
+
+			return inner.DB().Create(&User{ID: "uow-1", Password: "rahasia", Name: Name{FirstName: "Duplikat"}}).Error
+		})
+		assert.NotNil(t, err)
+
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// callback dari nested yang gagal tidak dijalankan
+	assert.Equal(t, []string{"uow-1"}, committed)
+
+	var count int64
+	err = db.Model(&User{}).Where("id IN ?", []string{"uow-1", "uow-2"}).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	err = db.Delete(&User{}, "id = ?", "uow-1").Error
+	assert.Nil(t, err)
+}
+
+// implementasi unit of work, semua step yang gagal dilaporkan dan transaction di rollback
+func TestUnitOfWorkStepErrors(t *testing.T) {
+	called := false
+
+	err := RunUnitOfWork(context.Background(), db, func(uow *UnitOfWork) error {
+		uow.Step("create user 18", func(tx *gorm.DB) error {
+			return tx.Create(&User{ID: "uow-18", Password: "rahasia", Name: Name{FirstName: "User 18"}}).Error
+		})
+		uow.Step("create duplicate user 1", func(tx *gorm.DB) error {
+			return tx.Create(&User{ID: "1", Password: "rahasia", Name: Name{FirstName: "Duplikat"}}).Error
+		})
+		uow.Step("query unknown table", func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE tabel_tidak_ada SET nama = ?", "x").Error
+		})
+		uow.AfterCommit(func() { called = true })
+		return nil
+	})
+
+	var uowErr *UnitOfWorkError
+	assert.True(t, errors.As(err, &uowErr))
+	assert.Equal(t, 2, len(uowErr.Steps))
+	assert.Equal(t, "create duplicate user 1", uowErr.Steps[0].Step)
+	assert.Equal(t, "query unknown table", uowErr.Steps[1].Step)
+	assert.False(t, called)
+
+	// step yang berhasil juga ikut di rollback
+	var count int64
+	err = db.Model(&User{}).Where("id = ?", "uow-18").Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+}
+
+// model untuk pengujian schema drift
+type SchemaDiffSample struct {
+	ID        int64      `gorm:"primary_key;column:id;autoIncrement"`
+	Code      string     `gorm:"column:code;size:50;uniqueIndex"`
+	Note      string     `gorm:"column:note;size:200"`
+	DeletedAt *time.Time `gorm:"column:deleted_at"`
+}
+
+func (s *SchemaDiffSample) TableName() string {
+	return "schema_diff_samples"
+}
+
+// implementasi deteksi schema drift
+func TestSchemaDiff(t *testing.T) {
+	migrator := db.Migrator()
+	err := migrator.DropTable(&SchemaDiffSample{})
+	assert.Nil(t, err)
+	err = migrator.AutoMigrate(&SchemaDiffSample{})
+	assert.Nil(t, err)
+	defer migrator.DropTable(&SchemaDiffSample{})
+
+	// setelah auto migrate tidak ada perbedaan
+	report, err := schemadiff.Compare(db, &SchemaDiffSample{})
+	assert.Nil(t, err)
+	assert.False(t, report.HasDrift())
+
+	// mengubah database tanpa mengubah model
+	err = db.Exec("ALTER TABLE schema_diff_samples DROP COLUMN note, ADD COLUMN legacy VARCHAR(10), " +
+		"MODIFY code VARCHAR(20), MODIFY deleted_at DATETIME(3) NOT NULL, DROP INDEX idx_schema_diff_samples_code").Error
+	assert.Nil(t, err)
+
+	report, err = schemadiff.Compare(db, &SchemaDiffSample{})
+	assert.Nil(t, err)
+	assert.True(t, report.HasDrift())
+
+	kinds := map[schemadiff.Kind]string{}
+	for _, issue := range report.Issues {
+		kinds[issue.Kind] = issue.Column + issue.Index
+	}
+	assert.Equal(t, "note", kinds[schemadiff.MissingColumn])
+	assert.Equal(t, "legacy", kinds[schemadiff.ExtraColumn])
+	assert.Equal(t, "code", kinds[schemadiff.TypeMismatch])
+	assert.Equal(t, "deleted_at", kinds[schemadiff.NullabilityMismatch])
+	assert.Equal(t, "idx_schema_diff_samples_code", kinds[schemadiff.MissingIndex])
+
+	var builder strings.Builder
+	err = report.WriteText(&builder)
+	assert.Nil(t, err)
+	assert.Contains(t, builder.String(), "missing_column schema_diff_samples.note")
+}
+
+// implementasi ER diagram dan data dictionary dari model
+func TestERDiagram(t *testing.T) {
+	diagram, err := erd.Parse(schema.NamingStrategy{}, Models()...)
+	assert.Nil(t, err)
+
+	var mermaid strings.Builder
+	err = diagram.WriteMermaid(&mermaid)
+	assert.Nil(t, err)
+	assert.Contains(t, mermaid.String(), `users ||--o| wallets : "user_id"`)
+	assert.Contains(t, mermaid.String(), `users ||--o{ addresses : "user_id"`)
+	assert.Contains(t, mermaid.String(), `products ||--o{ user_like_product : "product_id"`)
+
+	// dokumentasi di folder docs harus sama dengan hasil generate (jalankan go generate jika gagal)
+	var markdown strings.Builder
+	err = diagram.WriteMarkdown(&markdown)
+	assert.Nil(t, err)
+
+	docs, err := os.ReadFile("docs/data-dictionary.md")
+	assert.Nil(t, err)
+	assert.Equal(t, string(docs), markdown.String())
+}
+
+// implementasi generator model dari database
+func TestModelGenerator(t *testing.T) {
+	err := db.Exec("DROP TABLE IF EXISTS gen_wallets, gen_users").Error
+	assert.Nil(t, err)
+	err = db.Exec("CREATE TABLE gen_users (id BIGINT AUTO_INCREMENT PRIMARY KEY, name VARCHAR(100) NOT NULL, visits INT UNSIGNED NOT NULL, bio TEXT NULL, " +
+		"created_at DATETIME(3) NOT NULL, updated_at BIGINT NOT NULL, deleted_at DATETIME(3) NULL)").Error
+	assert.Nil(t, err)
+	err = db.Exec("CREATE TABLE gen_wallets (id VARCHAR(100) PRIMARY KEY, gen_user_id BIGINT NOT NULL, balance BIGINT NOT NULL, " +
+		"CONSTRAINT fk_gen_wallets_user FOREIGN KEY (gen_user_id) REFERENCES gen_users (id))").Error
+	assert.Nil(t, err)
+	defer db.Exec("DROP TABLE IF EXISTS gen_wallets, gen_users")
+
+	dir := t.TempDir()
+	options := modelgen.Options{Dir: dir, Package: "models", Tables: []string{"gen_users", "gen_wallets"}}
+
+	// method yang ditulis manual tidak di generate ulang
+	custom := "package models\n\nfunc (w GenWallet) TableName() string {\n\treturn \"gen_wallets\"\n}\n"
+	err = os.WriteFile(dir+"/gen_wallet.go", []byte(custom), 0o644)
+	assert.Nil(t, err)
+
+	result, err := modelgen.Generate(db, options)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Written))
+
+	users, err := os.ReadFile(dir + "/gen_users_gen.go")
+	assert.Nil(t, err)
+	assert.Contains(t, string(users), "// Code generated by modelgen. DO NOT EDIT.")
+	assert.Contains(t, string(users), "ID        int64          `gorm:\"column:id;primary_key;autoIncrement\"`")
+	assert.Contains(t, string(users), "Name      string         `gorm:\"column:name;size:100\"`")
+	assert.Contains(t, string(users), "Bio       *string")
+	assert.Contains(t, string(users), "Visits    uint32 ")
+	assert.Contains(t, string(users), "`gorm:\"column:created_at;autoCreateTime\"`")
+	assert.Contains(t, string(users), "`gorm:\"column:updated_at;autoCreateTime:milli;autoUpdateTime:milli\"`")
+	assert.Contains(t, string(users), "DeletedAt gorm.DeletedAt")
+	assert.Contains(t, string(users), "GenWallets []GenWallet `gorm:\"foreignKey:gen_user_id;references:id\"`")
+	assert.Contains(t, string(users), "func (g *GenUser) TableName() string")
+
+	wallets, err := os.ReadFile(dir + "/gen_wallets_gen.go")
+	assert.Nil(t, err)
+	assert.Contains(t, string(wallets), "GenUser *GenUser `gorm:\"foreignKey:gen_user_id;references:id\"`")
+	assert.NotContains(t, string(wallets), "TableName")
+
+	// generate ulang menghasilkan file yang sama
+	_, err = modelgen.Generate(db, options)
+	assert.Nil(t, err)
+	again, err := os.ReadFile(dir + "/gen_users_gen.go")
+	assert.Nil(t, err)
+	assert.Equal(t, string(users), string(again))
+
+	// struct yang ditulis manual tidak di generate
+	err = os.WriteFile(dir+"/gen_user.go", []byte("package models\n\ntype GenUser struct {\n\tID int64\n}\n"), 0o644)
+	assert.Nil(t, err)
+	result, err = modelgen.Generate(db, options)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"gen_users"}, result.Skipped)
+}
+
+// tipe integer dipetakan berdasarkan nama tipe yang utuh, INTEGER sqlite menjadi int64
+func TestModelGeneratorIntegerTypes(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/modelgen.db"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	err = conn.Exec("CREATE TABLE gen_types (id INTEGER PRIMARY KEY AUTOINCREMENT, level TINYINT NOT NULL, small SMALLINT NOT NULL, " +
+		"total INT NOT NULL, location POINT NOT NULL, period INTERVAL NOT NULL)").Error
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	_, err = modelgen.Generate(conn, modelgen.Options{Dir: dir, Package: "models", Tables: []string{"gen_types"}})
+	require.NoError(t, err)
+
+	source, err := os.ReadFile(dir + "/gen_types_gen.go")
+	require.NoError(t, err)
+	assert.Regexp(t, `ID +int64 `, string(source))
+	assert.Regexp(t, `Level +int8 `, string(source))
+	assert.Regexp(t, `Small +int16 `, string(source))
+	assert.Regexp(t, `Total +int32 `, string(source))
+	assert.Regexp(t, `Location +string `, string(source))
+	assert.Regexp(t, `Period +string `, string(source))
+}
+
+// implementasi delete policy pada relasi user
+func TestDeletePolicy(t *testing.T) {
+	user := User{
+		ID:       "delete-policy-1",
+		Password: "rahasia",
+		Name:     Name{FirstName: "Delete", LastName: "Policy"},
+		Wallet:   Wallet{ID: "delete-policy-wallet-1", Balance: 1000},
+		Addresses: []Address{
+			{Address: "Jalan Satu"},
+			{Address: "Jalan Dua"},
+		},
+		Todos:        []Todo{{Title: "Todo", Description: "Delete policy"}},
+		LikeProducts: []Product{{ID: "delete-policy-product-1", Name: "Product", Price: 1000}},
+	}
+	err := db.Create(&user).Error
+	assert.Nil(t, err)
+	defer db.Unscoped().Delete(&Todo{}, "user_id = ?", user.ID)
+	defer db.Delete(&Product{}, "id = ?", "delete-policy-product-1")
+
+	// user masih memiliki wallet (restrict), sehingga tidak boleh di hapus
+	err = db.Delete(&user).Error
+	assert.True(t, errors.Is(err, ErrDeleteRestricted))
+
+	var restricted *RestrictedDeleteError
+	assert.True(t, errors.As(err, &restricted))
+	assert.Equal(t, "users", restricted.Table)
+	assert.Equal(t, []BlockingRelation{{Relation: "Wallet", Table: "wallets", Count: 1}}, restricted.Blocking)
+
+	var count int64
+	err = db.Model(&Address{}).Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+
+	// setelah wallet di hapus, address dan like product ikut di hapus dan todo di soft delete
+	err = db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Delete(&Wallet{}, "id = ?", user.Wallet.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Delete(&user).Error
+	})
+	assert.Nil(t, err)
+
+	err = db.Model(&Address{}).Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	err = db.Table("user_like_product").Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	err = db.Model(&Todo{}).Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	err = db.Unscoped().Model(&Todo{}).Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// constraint database dari delete policy (soft_cascade tidak dibuat sebagai constraint)
+	constraints, err := DeleteConstraints(db, &User{})
+	assert.Nil(t, err)
+	assert.Contains(t, constraints, DeleteConstraint{
+		Name: "fk_users_wallet", Table: "wallets", Column: "user_id",
+		ReferencedTable: "users", ReferencedColumn: "id", OnDelete: "RESTRICT",
+	})
+	for _, constraint := range constraints {
+		assert.NotEqual(t, "todos", constraint.Table)
+	}
+}
+
+// model untuk pengujian delete policy set_null dan cascade di level database
+type PolicyOwner struct {
+	ID    string       `gorm:"primary_key;column:id;size:100"`
+	Notes []PolicyNote `gorm:"foreignKey:owner_id;references:id;deletePolicy:set_null"`
+	Items []PolicyItem `gorm:"foreignKey:owner_id;references:id;deletePolicy:cascade"`
+	Tags  []PolicyTag  `gorm:"many2many:policy_owner_tags;foreignKey:id;joinForeignKey:owner_id;references:id;joinReferences:tag_id;deletePolicy:cascade"`
+}
+
+type PolicyNote struct {
+	ID      int64   `gorm:"primary_key;column:id;autoIncrement"`
+	OwnerId *string `gorm:"column:owner_id;size:100"`
+}
+
+type PolicyItem struct {
+	ID      int64  `gorm:"primary_key;column:id;autoIncrement"`
+	OwnerId string `gorm:"column:owner_id;size:100"`
+}
+
+type PolicyTag struct {
+	ID string `gorm:"primary_key;column:id;size:100"`
+}
+
+// delete policy yang dijalankan oleh DeletePolicyEnforcer (sqlite tidak menggunakan foreign key constraint)
+func TestDeletePolicyEnforcer(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/policy.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.Use(NewDeletePolicyEnforcer()))
+	assert.Nil(t, conn.AutoMigrate(&PolicyOwner{}, &PolicyNote{}, &PolicyItem{}, &PolicyTag{}))
+
+	// tabel penghubung belum ada, policy cascade pada Tags dilewati
+	assert.Nil(t, conn.Migrator().DropTable("policy_owner_tags"))
+
+	owner := PolicyOwner{ID: "owner-1", Notes: []PolicyNote{{}, {}}, Items: []PolicyItem{{}}}
+	assert.Nil(t, conn.Omit("Tags").Create(&owner).Error)
+
+	// set_null : foreign key pada note di isi NULL, cascade : item ikut di hapus
+	assert.Nil(t, conn.Delete(&owner).Error)
+
+	var notes []PolicyNote
+	assert.Nil(t, conn.Find(&notes).Error)
+	assert.Equal(t, 2, len(notes))
+	for _, note := range notes {
+		assert.Nil(t, note.OwnerId)
+	}
+
+	var count int64
+	assert.Nil(t, conn.Model(&PolicyItem{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// setelah tabel penghubung dibuat, policy cascade pada Tags kembali dijalankan
+	assert.Nil(t, conn.AutoMigrate(&PolicyOwner{}))
+	owner = PolicyOwner{ID: "owner-2", Tags: []PolicyTag{{ID: "tag-1"}}}
+	assert.Nil(t, conn.Create(&owner).Error)
+	assert.Nil(t, conn.Delete(&owner).Error)
+
+	assert.Nil(t, conn.Table("policy_owner_tags").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+// foreign key constraint dari delete policy di database (mysql)
+func TestMigrateDeletePolicies(t *testing.T) {
+	// tanpa enforcer, agar yang diuji adalah ON DELETE pada database
+	conn := db.Session(&gorm.Session{NewDB: true})
+	err := conn.Migrator().DropTable("policy_owner_tags", &PolicyNote{}, &PolicyItem{}, &PolicyTag{}, &PolicyOwner{})
+	assert.Nil(t, err)
+	defer conn.Migrator().DropTable("policy_owner_tags", &PolicyNote{}, &PolicyItem{}, &PolicyTag{}, &PolicyOwner{})
+
+	// AutoMigrate membuat constraint bawaan gorm tanpa ON DELETE
+	err = conn.AutoMigrate(&PolicyOwner{}, &PolicyNote{}, &PolicyItem{}, &PolicyTag{})
+	assert.Nil(t, err)
+
+	// migrasi dijalankan dua kali, lalu AutoMigrate lagi, constraint tidak boleh bertambah
+	assert.Nil(t, MigrateDeletePolicies(conn, &PolicyOwner{}))
+	assert.Nil(t, MigrateDeletePolicies(conn, &PolicyOwner{}))
+	assert.Nil(t, conn.AutoMigrate(&PolicyOwner{}, &PolicyNote{}, &PolicyItem{}))
+
+	type foreignKey struct {
+		TableName  string
+		DeleteRule string
+	}
+	var foreignKeys []foreignKey
+	err = conn.Raw("SELECT table_name, delete_rule FROM information_schema.referential_constraints "+
+		"WHERE constraint_schema = DATABASE() AND referenced_table_name = ? ORDER BY table_name", "policy_owners").
+		Scan(&foreignKeys).Error
+	assert.Nil(t, err)
+	assert.Equal(t, []foreignKey{
+		{TableName: "policy_items", DeleteRule: "CASCADE"},
+		{TableName: "policy_notes", DeleteRule: "SET NULL"},
+		{TableName: "policy_owner_tags", DeleteRule: "CASCADE"},
+	}, foreignKeys)
+
+	owner := PolicyOwner{ID: "owner-1", Notes: []PolicyNote{{}}, Items: []PolicyItem{{}}, Tags: []PolicyTag{{ID: "tag-1"}}}
+	assert.Nil(t, conn.Create(&owner).Error)
+
+	// delete dengan raw sql, sehingga hanya constraint database yang menjalankan policy
+	assert.Nil(t, conn.Exec("DELETE FROM policy_owners WHERE id = ?", owner.ID).Error)
+
+	var note PolicyNote
+	assert.Nil(t, conn.Take(&note, "id = ?", owner.Notes[0].ID).Error)
+	assert.Nil(t, note.OwnerId)
+
+	var count int64
+	assert.Nil(t, conn.Model(&PolicyItem{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.Nil(t, conn.Table("policy_owner_tags").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+// implementasi export dan penghapusan data pribadi user (GDPR)
+func TestUserDataPrivacy(t *testing.T) {
+	err := db.Migrator().AutoMigrate(&ErasureAudit{})
+	assert.Nil(t, err)
+
+	user := User{
+		ID:        "gdpr-1",
+		Password:  "rahasia",
+		Name:      Name{FirstName: "Data", LastName: "Pribadi"},
+		Wallet:    Wallet{ID: "gdpr-wallet-1", Balance: 5000},
+		Addresses: []Address{{Address: "Jalan Pribadi No. 1"}},
+		Todos:     []Todo{{Title: "Todo 1"}, {Title: "Todo 2"}},
+	}
+	err = db.Create(&user).Error
+	assert.Nil(t, err)
+	err = db.Delete(&user.Todos[1]).Error
+	assert.Nil(t, err)
+
+	userLog, err := NewUserLog(user.ID, ActionLogin, LoginPayload{IP: "127.0.0.1"})
+	assert.Nil(t, err)
+	err = db.Create(&userLog).Error
+	assert.Nil(t, err)
+	err = db.Create(&GuestBook{Name: "Data", Email: "gdpr@example.com", Message: "Halo"}).Error
+	assert.Nil(t, err)
+
+	defer db.Delete(&Wallet{}, "id = ?", user.Wallet.ID)
+	defer db.Delete(&UserLog{}, "id = ?", userLog.ID)
+
+	privacy := NewUserDataPrivacy(db, DefaultErasurePolicy())
+
+	// export
+	data, err := privacy.ExportUserData(context.Background(), user.ID, "gdpr@example.com")
+	assert.Nil(t, err)
+
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	assert.Nil(t, err)
+
+	files := map[string]json.RawMessage{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		assert.Nil(t, err)
+		content, err := io.ReadAll(reader)
+		assert.Nil(t, err)
+		files[file.Name] = content
+	}
+	assert.Equal(t, 8, len(files))
+	assert.NotContains(t, string(files["user.json"]), "password")
+	assert.Contains(t, string(files["user.json"]), `"first_name": "Data"`)
+	assert.Contains(t, string(files["wallet.json"]), `"balance": 5000`)
+	assert.Contains(t, string(files["addresses.json"]), "Jalan Pribadi No. 1")
+	assert.Contains(t, string(files["guest_books.json"]), "gdpr@example.com")
+
+	var todos []map[string]interface{}
+	err = json.Unmarshal(files["todos.json"], &todos)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(todos)) // termasuk todo yang sudah di soft delete
+
+	// menghapus wallet yang masih memiliki saldo akan mengubah total ledger, sehingga dibatalkan
+	policy := DefaultErasurePolicy()
+	policy.Wallet = ErasureDelete
+	_, err = NewUserDataPrivacy(db, policy).EraseUser(context.Background(), user.ID)
+	assert.True(t, errors.Is(err, ErrLedgerImbalance))
+
+	var count int64
+	err = db.Model(&User{}).Where("id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// erase dengan policy default
+	audit, err := privacy.EraseUser(context.Background(), user.ID, "gdpr@example.com")
+	require.NoError(t, err)
+	defer db.Delete(&ErasureAudit{}, "id = ?", audit.ID)
+	defer db.Exec("DELETE FROM users WHERE id = ?", audit.Pseudonym)
+
+	subjectHash, err := ErasureSubjectHash(user.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, subjectHash, audit.SubjectHash)
+
+	err = db.Model(&User{}).Where("id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	err = db.Unscoped().Model(&Todo{}).Where("user_id = ?", user.ID).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	err = db.Model(&GuestBook{}).Where("email = ?", "gdpr@example.com").Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), count)
+
+	// wallet dan user log tetap ada dengan user_id pseudonym, saldo tidak berubah
+	var wallet Wallet
+	err = db.Take(&wallet, "id = ?", user.Wallet.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Pseudonym, wallet.UserId)
+	assert.Equal(t, int64(5000), wallet.Balance)
+
+	var anonymized UserLog
+	err = db.Take(&anonymized, "id = ?", userLog.ID).Error
+	assert.Nil(t, err)
+	assert.Equal(t, audit.Pseudonym, anonymized.UserId)
+	assert.Nil(t, anonymized.Payload)
+
+	// wallet yang di anonimkan dipindahkan ke user tombstone, agar foreign key tetap valid
+	var tombstone User
+	err = db.Take(&tombstone, "id = ?", audit.Pseudonym).Error
+	assert.Nil(t, err)
+	assert.Equal(t, "", tombstone.Name.FirstName)
+}
+
+// penghapusan data pribadi dengan policy selain default, foreign key constraint di sqlite diaktifkan
+func TestUserDataPrivacyPolicy(t *testing.T) {
+	// keyring di atur sendiri karena test ini tidak menggunakan OpenConnection()
+	oldKeyring := encryptionKeyring.Load()
+	keyring, err := NewKeyring("test-1", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+	})
+	require.NoError(t, err)
+	SetEncryptionKeyring(keyring)
+	defer SetEncryptionKeyring(oldKeyring)
+
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/gdpr.db?_foreign_keys=1"), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	assert.Nil(t, conn.Use(NewDeletePolicyEnforcer()))
+	assert.Nil(t, conn.AutoMigrate(Models()...))
+
+	// keep tidak bisa digunakan untuk relasi dengan delete policy ketika user di hapus
+	for _, policy := range []ErasurePolicy{
+		{User: ErasureDelete, Wallet: ErasureKeep, Addresses: ErasureDelete, LikedProducts: ErasureDelete, Todos: ErasureDelete, UserLogs: ErasureKeep, GuestBooks: ErasureKeep},
+		{User: ErasureDelete, Wallet: ErasureAnonymize, Addresses: ErasureKeep, LikedProducts: ErasureDelete, Todos: ErasureDelete, UserLogs: ErasureKeep, GuestBooks: ErasureKeep},
+		{User: ErasureDelete, Wallet: ErasureAnonymize, Addresses: ErasureDelete, LikedProducts: ErasureDelete, Todos: ErasureKeep, UserLogs: ErasureKeep, GuestBooks: ErasureKeep},
+	} {
+		_, err = NewUserDataPrivacy(conn, policy).EraseUser(context.Background(), "gdpr-1")
+		assert.ErrorContains(t, err, "can not be kept when user is deleted")
+	}
+
+	user := User{
+		ID:        "gdpr-1",
+		Name:      Name{FirstName: "Data", LastName: "Pribadi"},
+		Wallet:    Wallet{ID: "gdpr-wallet-1", Balance: 5000},
+		Addresses: []Address{{Address: "Jalan Pribadi No. 1"}},
+		Todos:     []Todo{{Title: "Todo 1"}},
+	}
+	assert.Nil(t, conn.Create(&user).Error)
+
+	// wallet, address dan todo di anonimkan, user tetap di hapus
+	policy := ErasurePolicy{
+		User:          ErasureDelete,
+		Wallet:        ErasureAnonymize,
+		Addresses:     ErasureAnonymize,
+		LikedProducts: ErasureDelete,
+		Todos:         ErasureAnonymize,
+		UserLogs:      ErasureDelete,
+		GuestBooks:    ErasureKeep,
+	}
+	audit, err := NewUserDataPrivacy(conn, policy).EraseUser(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	var count int64
+	assert.Nil(t, conn.Model(&User{}).Where("id = ?", user.ID).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// audit tidak menyimpan sha256 biasa dari user id, dan tetap bisa dicari setelah rotasi key
+	plain := sha256.Sum256([]byte(user.ID))
+	assert.NotEqual(t, hex.EncodeToString(plain[:]), audit.SubjectHash)
+
+	rotated, err := NewKeyring("test-2", map[string][]byte{
+		"test-1": []byte("0123456789abcdef0123456789abcdef"),
+		"test-2": []byte("fedcba9876543210fedcba9876543210"),
+	})
+	require.NoError(t, err)
+	SetEncryptionKeyring(rotated)
+
+	var found ErasureAudit
+	assert.Nil(t, conn.Scopes(ErasureSubjectEquals(user.ID)).Take(&found).Error)
+	assert.Equal(t, audit.ID, found.ID)
+
+	var wallet Wallet
+	assert.Nil(t, conn.Take(&wallet, "id = ?", user.Wallet.ID).Error)
+	assert.Equal(t, audit.Pseudonym, wallet.UserId)
+	assert.Equal(t, int64(5000), wallet.Balance)
+
+	var address Address
+	assert.Nil(t, conn.Take(&address, "id = ?", user.Addresses[0].ID).Error)
+	assert.Equal(t, audit.Pseudonym, address.UserId)
+	assert.Equal(t, "", address.Address)
+
+	var todo Todo
+	assert.Nil(t, conn.Take(&todo, "id = ?", user.Todos[0].ID).Error)
+	assert.Equal(t, audit.Pseudonym, todo.UserId)
+
+	// data yang di anonimkan menunjuk ke user tombstone, sehingga tidak ada foreign key yang rusak
+	var violations []map[string]interface{}
+	assert.Nil(t, conn.Raw("PRAGMA foreign_key_check").Scan(&violations).Error)
+	assert.Equal(t, 0, len(violations))
+
+	var steps []ErasureStep
+	assert.Nil(t, json.Unmarshal([]byte(audit.Summary), &steps))
+	assert.Contains(t, steps, ErasureStep{Data: "addresses", Action: ErasureAnonymize, Rows: 1})
+	assert.Contains(t, steps, ErasureStep{Data: "todos", Action: ErasureAnonymize, Rows: 1})
+}
+
+// model untuk pengujian integritas referensial, tabel dibuat tanpa foreign key constraint
+type IntegrityOwner struct {
+	ID        string           `gorm:"primary_key;column:id"`
+	DeletedAt gorm.DeletedAt   `gorm:"column:deleted_at"`
+	Account   IntegrityAccount `gorm:"foreignKey:owner_id;references:id"`
+	Tags      []IntegrityTag   `gorm:"many2many:integrity_owner_tags;foreignKey:id;joinForeignKey:owner_id;references:id;joinReferences:tag_id"`
+}
+
+type IntegrityAccount struct {
+	ID        int64          `gorm:"primary_key;column:id;autoIncrement"`
+	OwnerId   string         `gorm:"column:owner_id"`
+	DeletedAt gorm.DeletedAt `gorm:"column:deleted_at"`
+}
+
+type IntegrityTag struct {
+	ID string `gorm:"primary_key;column:id"`
+}
+
+// implementasi pemeriksaan dan perbaikan integritas referensial
+func TestIntegrity(t *testing.T) {
+	statements := []string{
+		"DROP TABLE IF EXISTS integrity_owner_tags, integrity_accounts, integrity_tags, integrity_owners",
+		"CREATE TABLE integrity_owners (id VARCHAR(100) PRIMARY KEY, deleted_at DATETIME(3) NULL)",
+		"CREATE TABLE integrity_accounts (id BIGINT AUTO_INCREMENT PRIMARY KEY, owner_id VARCHAR(100), deleted_at DATETIME(3) NULL)",
+		"CREATE TABLE integrity_tags (id VARCHAR(100) PRIMARY KEY)",
+		"CREATE TABLE integrity_owner_tags (owner_id VARCHAR(100), tag_id VARCHAR(100), PRIMARY KEY (owner_id, tag_id))",
+		"INSERT INTO integrity_owners (id, deleted_at) VALUES ('o1', NULL), ('o2', NOW())",
+		"INSERT INTO integrity_accounts (owner_id) VALUES ('o1'), ('o1'), ('o2'), ('missing')",
+		"INSERT INTO integrity_tags (id) VALUES ('t1')",
+		"INSERT INTO integrity_owner_tags (owner_id, tag_id) VALUES ('o1', 't1'), ('missing', 't1'), ('o1', 't-missing')",
+	}
+	for _, statement := range statements {
+		err := db.Exec(statement).Error
+		assert.Nil(t, err)
+	}
+	defer db.Exec("DROP TABLE IF EXISTS integrity_owner_tags, integrity_accounts, integrity_tags, integrity_owners")
+
+	ctx := context.Background()
+	report, err := integrity.Scan(ctx, db, &IntegrityOwner{}, &IntegrityAccount{}, &IntegrityTag{})
+	assert.Nil(t, err)
+
+	issues := map[string]integrity.Issue{}
+	for _, issue := range report.Issues {
+		issues[string(issue.Kind)+" "+issue.Column] = issue
+	}
+	assert.Equal(t, 5, len(report.Issues))
+	assert.Equal(t, []string{"missing"}, issues["orphan_child owner_id"].Samples)
+	assert.Equal(t, []string{"o1"}, issues["duplicate_child owner_id"].Samples)
+	assert.Equal(t, []string{"o2"}, issues["soft_deleted_parent owner_id"].Samples)
+	assert.Equal(t, []string{"missing"}, issues["dangling_join owner_id"].Samples)
+	assert.Equal(t, []string{"t-missing"}, issues["dangling_join tag_id"].Samples)
+
+	// duplicate wallet / account butuh keputusan manual, sisanya diperbaiki otomatis
+	// account orphan di soft delete karena account memiliki kolom deleted_at
+	plan := integrity.Plan(report)
+	actions := map[integrity.Kind]integrity.RepairAction{}
+	for _, step := range plan.Steps {
+		actions[step.Issue.Kind] = step.Action
+	}
+	assert.Equal(t, integrity.RepairSoftDelete, actions[integrity.OrphanChild])
+	assert.Equal(t, integrity.RepairDelete, actions[integrity.DanglingJoin])
+	assert.Equal(t, integrity.RepairSoftDelete, actions[integrity.SoftDeletedParent])
+	assert.Equal(t, integrity.RepairManual, actions[integrity.DuplicateChild])
+
+	results, err := plan.Execute(ctx, db, 1)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(results))
+
+	report, err = integrity.Scan(ctx, db, &IntegrityOwner{}, &IntegrityAccount{}, &IntegrityTag{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(report.Issues))
+	assert.Equal(t, integrity.DuplicateChild, report.Issues[0].Kind)
+
+	// account milik owner yang di soft delete dan account orphan ikut di soft delete, bukan di hapus
+	var count int64
+	err = db.Unscoped().Model(&IntegrityAccount{}).Where("owner_id IN ?", []string{"o2", "missing"}).Count(&count).Error
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2), count)
+}
+
+// wallet orphan (contoh milik user yang sudah di hapus) tidak di hapus otomatis, karena akan mengubah total saldo
+func TestIntegrityLedger(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/integrity.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+	assert.Nil(t, conn.AutoMigrate(&User{}, &Wallet{}))
+	assert.Nil(t, conn.Exec("INSERT INTO wallets (id, user_id, balance) VALUES ('w1', 'missing', 5000)").Error)
+
+	ctx := context.Background()
+	report, err := integrity.Scan(ctx, conn, &User{}, &Wallet{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(report.Issues))
+	assert.Equal(t, integrity.OrphanChild, report.Issues[0].Kind)
+	assert.Equal(t, "balance", report.Issues[0].ChildLedger)
+
+	plan := integrity.Plan(report)
+	assert.Equal(t, integrity.RepairManual, plan.Steps[0].Action)
+
+	results, err := plan.Execute(ctx, conn, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(results))
+
+	var total int64
+	assert.Nil(t, conn.Model(&Wallet{}).Select("SUM(balance)").Scan(&total).Error)
+	assert.Equal(t, int64(5000), total)
+}
+
+// EXPLAIN di dalam transaction menggunakan koneksi transaction, sehingga tabel yang belum di commit tetap terlihat
+func TestExplainTransaction(t *testing.T) {
+	conn, err := gorm.Open(sqlite.Open(t.TempDir()+"/explain.db"), &gorm.Config{Logger: logger.Discard})
+	assert.Nil(t, err)
+
+	err = conn.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("CREATE TABLE explain_samples (id INTEGER PRIMARY KEY, name TEXT)").Error
+		if err != nil {
+			return err
+		}
+
+		plan, err := explain.ExplainSQL(context.Background(), tx, "SELECT * FROM explain_samples WHERE name = ?", "sample")
+		if err != nil {
+			return err
+		}
+		assert.True(t, plan.HasWarnings())
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+// menyimpan pesan error dari assertion (explain.AssertEfficient, QueryScope.Assert) tanpa menggagalkan test
+type errorRecorder struct {
+	errors []string
+}
+
+func (r *errorRecorder) Helper() {}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// implementasi analisa query plan dengan EXPLAIN
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+
+	// query pada TestCount, sql diambil tanpa menjalankan query (DryRun)
+	var count int64
+	plan, err := explain.Analyze(ctx, db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&User{}).Joins("Wallet").Where("Wallet.balance > ?", 500000).Count(&count)
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "mysql", plan.Dialect)
+	assert.Contains(t, plan.SQL, "LEFT JOIN `wallets` `Wallet`")
+	assert.Equal(t, []interface{}{500000}, plan.Vars)
+	assert.Equal(t, 2, len(plan.Steps))
+	assert.Equal(t, int64(0), count)
+
+	// kolom first_name tidak memiliki index
+	var users []User
+	plan, err = explain.Analyze(ctx, db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("first_name").Find(&users)
+	})
+	assert.Nil(t, err)
+	assert.True(t, plan.HasWarnings())
+	assert.False(t, plan.HasWarnings(explain.FullTableScan, explain.Filesort))
+	kinds := []explain.WarningKind{}
+	for _, warning := range plan.Warnings {
+		kinds = append(kinds, warning.Kind)
+	}
+	assert.Equal(t, []explain.WarningKind{explain.FullTableScan, explain.Filesort}, kinds)
+	assert.Equal(t, "users", plan.Warnings[0].Table)
+
+	var results []map[string]interface{}
+	plan, err = explain.Analyze(ctx, db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Model(&User{}).Select("first_name, count(*) AS total").Group("first_name").Find(&results)
+	})
+	assert.Nil(t, err)
+	assert.True(t, plan.HasWarnings(explain.FullTableScan))
+
+	// sebagai assertion pada test
+	var user User
+	explain.AssertEfficient(t, db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("id = ?", "1").Take(&user)
+	})
+
+	recorder := &errorRecorder{}
+	explain.AssertEfficient(recorder, db, func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("first_name").Find(&users)
+	}, explain.FullTableScan)
+	assert.Equal(t, 1, len(recorder.errors))
+	assert.Contains(t, recorder.errors[0], "filesort on users")
+}
+
+// implementasi deteksi N+1 query
+func TestNPlusOne(t *testing.T) {
+	ids := []string{"1", "2", "3"}
+
+	// membaca wallet dan addresses di dalam loop, satu query per user
+	ctx, scope := ContextWithQueryScope(context.Background(), t.Name())
+	var users []User
+	err := db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+	assert.Nil(t, err)
+	for _, id := range ids {
+		var wallets []Wallet
+		err = db.WithContext(ctx).Where("user_id = ?", id).Find(&wallets).Error
+		assert.Nil(t, err)
+
+		var addresses []Address
+		err = db.WithContext(ctx).Model(&User{ID: id}).Association("Addresses").Find(&addresses)
+		assert.Nil(t, err)
+	}
+
+	detections := scope.Detections(2)
+	assert.Equal(t, 2, len(detections))
+	suggestions := map[string]string{}
+	for _, detection := range detections {
+		assert.Equal(t, 3, detection.Count)
+		assert.Equal(t, 1, len(detection.CallSites))
+		assert.Contains(t, detection.CallSites[0], "gorm_test.go:")
+		suggestions[detection.Relation] = detection.Suggestion
+	}
+	assert.Equal(t, map[string]string{"User.Wallet": `Joins("Wallet")`, "User.Addresses": `Preload("Addresses")`}, suggestions)
+
+	// threshold lebih besar dari jumlah query
+	assert.Equal(t, 0, len(scope.Detections(3)))
+
+	recorder := &errorRecorder{}
+	scope.Assert(recorder, 2)
+	assert.Equal(t, 2, len(recorder.errors))
+
+	// dengan Joins dan Preload cukup satu query per relasi
+	ctx, scope = ContextWithQueryScope(context.Background(), t.Name())
+	err = db.WithContext(ctx).Joins("Wallet").Preload("Addresses").Where("users.id IN ?", ids).Find(&users).Error
+	assert.Nil(t, err)
+	scope.Assert(t, 2)
+
+	// query tanpa QueryScope tidak dicatat
+	_, ok := QueryScopeFromContext(context.Background())
+	assert.False(t, ok)
+
+	// per request dengan middleware
+	var reported []NPlusOneDetection
+	detector := NewNPlusOneDetector(NPlusOneConfig{OnDetect: func(scope string, detections []NPlusOneDetection) {
+		assert.Equal(t, "GET /users", scope)
+		reported = detections
+	}})
+	handler := detector.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, id := range ids {
+			var wallets []Wallet
+			db.WithContext(r.Context()).Where("user_id = ?", id).Find(&wallets)
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	assert.Equal(t, 1, len(reported))
+	assert.Equal(t, "wallets", reported[0].Table)
+}
@@ -0,0 +1,250 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// implementasi health check dan statistik connection pool
+// digunakan untuk melihat kondisi connection pool yang sudah di atur di OpenConnection()
+// (max open, max idle, lifetime dan idle time), sehingga pengaturan nya tidak lagi di tebak
+type HealthChecker struct {
+	db      *gorm.DB
+	timeout time.Duration
+}
+
+// membuat health checker baru, timeout adalah batas waktu ping ke database
+func NewHealthChecker(db *gorm.DB, timeout time.Duration) *HealthChecker {
+	return &HealthChecker{db: db, timeout: timeout}
+}
+
+// hasil health check
+type HealthReport struct {
+	Status   string    `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Latency  string    `json:"latency"`
+	Pool     PoolStats `json:"pool"`
+	Checked  time.Time `json:"checked_at"`
+	duration time.Duration
+}
+
+// statistik connection pool, diambil dari sql.DBStats
+type PoolStats struct {
+	MaxOpenConnections int   `json:"max_open_connections"`
+	OpenConnections    int   `json:"open_connections"`
+	InUse              int   `json:"in_use"`
+	Idle               int   `json:"idle"`
+	WaitCount          int64 `json:"wait_count"`
+	WaitDurationMillis int64 `json:"wait_duration_ms"`
+	MaxIdleClosed      int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed  int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed  int64 `json:"max_lifetime_closed"`
+}
+
+const (
+	HealthStatusUp   = "up"
+	HealthStatusDown = "down"
+)
+
+// melakukan ping ke database dengan timeout, sekaligus mengambil statistik connection pool
+func (h *HealthChecker) Health(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthStatusUp, Checked: time.Now()}
+
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		report.Status = HealthStatusDown
+		report.Error = err.Error()
+		return report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err = sqlDB.PingContext(ctx)
+	report.duration = time.Since(start)
+	report.Latency = report.duration.String()
+
+	if err != nil {
+		report.Status = HealthStatusDown
+		report.Error = err.Error()
+	}
+
+	stats := sqlDB.Stats()
+	report.Pool = PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDurationMillis: stats.WaitDuration.Milliseconds(),
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+
+	return report
+}
+
+// menulis hasil health check dalam format prometheus text
+func (r HealthReport) WritePrometheus(w io.Writer) error {
+	up := 0
+	if r.Status == HealthStatusUp {
+		up = 1
+	}
+
+	metrics := []struct {
+		name  string
+		help  string
+		kind  string
+		value string
+	}{
+		{"db_up", "Whether the last database ping succeeded.", "gauge", fmt.Sprint(up)},
+		{"db_ping_duration_seconds", "Duration of the last database ping.", "gauge", fmt.Sprint(r.duration.Seconds())},
+		{"db_pool_max_open_connections", "Maximum number of open connections to the database.", "gauge", fmt.Sprint(r.Pool.MaxOpenConnections)},
+		{"db_pool_open_connections", "Number of established connections, both in use and idle.", "gauge", fmt.Sprint(r.Pool.OpenConnections)},
+		{"db_pool_in_use_connections", "Number of connections currently in use.", "gauge", fmt.Sprint(r.Pool.InUse)},
+		{"db_pool_idle_connections", "Number of idle connections.", "gauge", fmt.Sprint(r.Pool.Idle)},
+		{"db_pool_wait_count_total", "Total number of connections waited for.", "counter", fmt.Sprint(r.Pool.WaitCount)},
+		{"db_pool_wait_duration_seconds_total", "Total time blocked waiting for a new connection.", "counter", fmt.Sprint(float64(r.Pool.WaitDurationMillis) / 1000)},
+	}
+
+	for _, m := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", m.name, m.help, m.name, m.kind, m.name, m.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	// jumlah koneksi yang ditutup, dibedakan berdasarkan alasan nya
+	_, err := fmt.Fprintf(w, "# HELP db_pool_closed_connections_total Total number of connections closed by the pool.\n"+
+		"# TYPE db_pool_closed_connections_total counter\n"+
+		"db_pool_closed_connections_total{reason=\"max_idle\"} %d\n"+
+		"db_pool_closed_connections_total{reason=\"max_idle_time\"} %d\n"+
+		"db_pool_closed_connections_total{reason=\"max_lifetime\"} %d\n",
+		r.Pool.MaxIdleClosed, r.Pool.MaxIdleTimeClosed, r.Pool.MaxLifetimeClosed)
+
+	return err
+}
+
+// http handler untuk health check dalam format json
+// status code 503 jika database tidak bisa di ping
+func (h *HealthChecker) JSONHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Health(r.Context())
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status != HealthStatusUp {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// http handler untuk statistik connection pool dalam format prometheus text
+func (h *HealthChecker) PrometheusHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Health(r.Context())
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		report.WritePrometheus(w)
+	})
+}
+
+// pengaturan connection pool, digunakan oleh OpenConnection() dan OpenConnectionWithRetry()
+type PoolConfig struct {
+	MaxOpenConns    int           // batas koneksi aktif bersamaan, request berikutnya menunggu (bukan membuat koneksi baru)
+	MaxIdleConns    int           // koneksi nganggur yang disimpan, agar request baru tidak perlu membuat koneksi
+	ConnMaxLifetime time.Duration // umur maksimal koneksi, setelah itu koneksi diganti dengan yang baru
+	ConnMaxIdleTime time.Duration // koneksi yang menganggur lebih lama dari ini akan ditutup
+}
+
+// pengaturan connection pool default : 100 koneksi aktif, 10 idle, umur 30 menit dan idle 5 menit
+func DefaultPoolConfig() PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    100,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
+
+// menerapkan pengaturan connection pool ke koneksi gorm
+func ConfigurePool(db *gorm.DB, pool PoolConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+
+	return nil
+}
+
+// jeda default sebelum percobaan kedua, agar retry tanpa batas tidak menjadi loop tanpa jeda
+const DefaultConnectDelay = 100 * time.Millisecond
+
+// pengaturan untuk membuka koneksi dengan retry
+type ConnectRetry struct {
+	MaxAttempts  int           // jumlah maksimal percobaan, 0 artinya terus mencoba sampai context selesai
+	InitialDelay time.Duration // jeda sebelum percobaan kedua, default DefaultConnectDelay
+	MaxDelay     time.Duration // jeda maksimal, jeda akan di kali 2 setiap kali gagal
+	Pool         *PoolConfig   // pengaturan connection pool, default DefaultPoolConfig()
+}
+
+// membuka koneksi database dengan retry dan backoff, berbeda dengan OpenConnection() yang langsung panic,-
+// cocok digunakan pada saat startup aplikasi ketika database belum siap
+func OpenConnectionWithRetry(ctx context.Context, dialector gorm.Dialector, config *gorm.Config, retry ConnectRetry) (*gorm.DB, error) {
+	delay := retry.InitialDelay
+	if delay <= 0 {
+		delay = DefaultConnectDelay
+	}
+
+	pool := DefaultPoolConfig()
+	if retry.Pool != nil {
+		pool = *retry.Pool
+	}
+
+	for attempt := 1; ; attempt++ {
+		db, err := gorm.Open(dialector, config)
+		if err == nil {
+			err = ConfigurePool(db, pool)
+			if err != nil {
+				return nil, err
+			}
+			return db, nil
+		}
+
+		// menutup koneksi yang gagal di ping, agar tidak bocor ketika mencoba lagi
+		if db != nil {
+			if sqlDB, dbErr := db.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+
+		if retry.MaxAttempts > 0 && attempt >= retry.MaxAttempts {
+			return nil, fmt.Errorf("connect database after %d attempts: %w", attempt, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("connect database: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay *= 2
+		if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+			delay = retry.MaxDelay
+		}
+	}
+}
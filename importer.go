@@ -0,0 +1,409 @@
+package belajar_go_lang_gorm
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi bulk import dari file csv atau ndjson
+// data dibaca baris per baris (streaming) sehingga file yang berukuran besar tidak perlu di muat ke memory,-
+// kemudian di insert per batch menggunakan upsert (clause.OnConflict)
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+// pengaturan import
+type ImportOptions struct {
+	Format ImportFormat
+
+	// memetakan nama kolom di file ke kolom database atau nama field, contoh : "nama_depan" => "Name.FirstName"
+	// jika tidak ada di mapping, maka nama kolom file dianggap sama dengan nama kolom database (first_name)
+	Mapping map[string]string
+
+	// kolom unik untuk upsert, default menggunakan primary key
+	ConflictColumns []string
+
+	// kolom yang di update ketika data sudah ada, jika kosong maka semua kolom di update
+	UpdateColumns []string
+
+	BatchSize int // jumlah baris per batch insert, default 500
+
+	// baris yang gagal (tidak valid ataupun gagal di insert) ditulis ke sini dalam format csv
+	RejectWriter io.Writer
+
+	// dry run : baris hanya dibaca dan di validasi, tidak ada yang di simpan ke database
+	DryRun bool
+
+	// lokasi file checkpoint, berisi nomor baris (baris fisik pada file) terakhir yang sudah diproses,-
+	// sehingga jika import terhenti, proses bisa dilanjutkan dari baris terakhir
+	CheckpointPath string
+}
+
+// hasil import
+type ImportResult struct {
+	Processed int // jumlah baris yang dibaca (tidak termasuk yang dilewati karena checkpoint)
+	Imported  int // jumlah baris yang berhasil di simpan (atau valid ketika dry run)
+	Rejected  int // jumlah baris yang ditolak
+	Skipped   int // jumlah baris yang dilewati karena sudah diproses sebelum nya
+}
+
+// model yang memiliki method Validate() akan di validasi sebelum di simpan
+type Validator interface {
+	Validate() error
+}
+
+// import data user, kolom embedded Name bisa menggunakan first_name, middle_name, last_name
+func ImportUsers(ctx context.Context, db *gorm.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	return Import[User](ctx, db, r, opts)
+}
+
+// import data product
+func ImportProducts(ctx context.Context, db *gorm.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	return Import[Product](ctx, db, r, opts)
+}
+
+// import data address
+func ImportAddresses(ctx context.Context, db *gorm.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	return Import[Address](ctx, db, r, opts)
+}
+
+// import data ke model T dari csv atau ndjson
+func Import[T any](ctx context.Context, db *gorm.DB, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+
+	s, err := schema.Parse(new(T), &sync.Map{}, db.NamingStrategy)
+	if err != nil {
+		return result, err
+	}
+
+	checkpoint, err := readCheckpoint(opts.CheckpointPath)
+	if err != nil {
+		return result, err
+	}
+
+	var rejects *csv.Writer
+	if opts.RejectWriter != nil {
+		rejects = csv.NewWriter(opts.RejectWriter)
+		rejects.Write([]string{"line", "error", "record"})
+		defer rejects.Flush()
+	}
+
+	reject := func(line int, raw string, rowErr error) {
+		result.Rejected++
+		if rejects != nil {
+			rejects.Write([]string{strconv.Itoa(line), rowErr.Error(), raw})
+		}
+	}
+
+	batch := make([]T, 0, opts.BatchSize)
+	batchLines := make([]int, 0, opts.BatchSize)
+	batchRaws := make([]string, 0, opts.BatchSize)
+	lastLine := checkpoint
+	savedLine := checkpoint
+
+	// menyimpan batch, lalu menulis checkpoint sampai baris terakhir yang dibaca
+	// checkpoint tetap ditulis walaupun batch kosong, agar baris yang ditolak setelah batch terakhir-
+	// tidak diproses dan dilaporkan ulang ketika import dilanjutkan
+	flush := func() error {
+		if !opts.DryRun && len(batch) > 0 {
+			err := upsertBatch(ctx, db, s, batch, opts)
+			if err != nil {
+				// jika satu batch gagal, simpan ulang per baris agar baris yang bermasalah bisa di ketahui
+				for i := range batch {
+					rowErr := upsertBatch(ctx, db, s, batch[i:i+1], opts)
+					if rowErr != nil {
+						reject(batchLines[i], batchRaws[i], rowErr)
+						continue
+					}
+					result.Imported++
+				}
+			} else {
+				result.Imported += len(batch)
+			}
+		} else {
+			result.Imported += len(batch)
+		}
+
+		batch = batch[:0]
+		batchLines = batchLines[:0]
+		batchRaws = batchRaws[:0]
+
+		// baris yang ditolak ditulis terlebih dahulu sebelum checkpoint
+		if rejects != nil {
+			rejects.Flush()
+			if err := rejects.Error(); err != nil {
+				return err
+			}
+		}
+
+		if opts.DryRun || lastLine == savedLine {
+			return nil
+		}
+
+		err := writeCheckpoint(opts.CheckpointPath, lastLine)
+		if err != nil {
+			return err
+		}
+		savedLine = lastLine
+
+		return nil
+	}
+
+	err = readRecords(r, opts.Format, func(line int, raw string, record map[string]interface{}, recordErr error) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// baris yang sudah diproses sebelum nya (berdasarkan checkpoint) dilewati
+		if line <= checkpoint {
+			result.Skipped++
+			return nil
+		}
+
+		result.Processed++
+		lastLine = line
+
+		var row T
+		rowErr := recordErr
+		if rowErr == nil {
+			rowErr = assignRecord(ctx, s, reflect.ValueOf(&row).Elem(), record, opts.Mapping)
+		}
+		if rowErr == nil {
+			if validator, ok := any(&row).(Validator); ok {
+				rowErr = validator.Validate()
+			}
+		}
+
+		if rowErr != nil {
+			reject(line, raw, rowErr)
+			return nil
+		}
+
+		batch = append(batch, row)
+		batchLines = append(batchLines, line)
+		batchRaws = append(batchRaws, raw)
+
+		if len(batch) >= opts.BatchSize {
+			return flush()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return result, err
+	}
+
+	return result, flush()
+}
+
+// menyimpan satu batch dengan upsert di dalam transaction
+func upsertBatch[T any](ctx context.Context, db *gorm.DB, s *schema.Schema, batch []T, opts ImportOptions) error {
+	onConflict := clause.OnConflict{UpdateAll: true}
+
+	conflictColumns := opts.ConflictColumns
+	if len(conflictColumns) == 0 {
+		conflictColumns = s.PrimaryFieldDBNames
+	}
+	for _, column := range conflictColumns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column})
+	}
+
+	if len(opts.UpdateColumns) > 0 {
+		onConflict.UpdateAll = false
+		onConflict.DoUpdates = clause.AssignmentColumns(opts.UpdateColumns)
+	}
+
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Omit(clause.Associations).Clauses(onConflict).Create(&batch).Error
+	})
+}
+
+// mengisi field pada struct berdasarkan data satu baris
+func assignRecord(ctx context.Context, s *schema.Schema, value reflect.Value, record map[string]interface{}, mapping map[string]string) error {
+	for key, data := range record {
+		name := key
+		if mapped, ok := mapping[key]; ok {
+			name = mapped
+		}
+
+		field := s.LookUpField(name)
+		if field == nil {
+			field = s.FieldsByBindName[name]
+		}
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("unknown column %q", key)
+		}
+
+		if data == nil || data == "" {
+			continue
+		}
+
+		err := field.Set(ctx, value, data)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// membaca file baris per baris, fn dipanggil untuk setiap baris data beserta nomor baris fisik nya pada file
+// (untuk csv, header adalah baris 1 dan record yang mengandung newline di dalam quote memakai baris awal nya)
+// baris yang tidak bisa di parse tetap diteruskan ke fn beserta error nya, agar bisa ditulis ke reject file
+func readRecords(r io.Reader, format ImportFormat, fn func(line int, raw string, record map[string]interface{}, recordErr error) error) error {
+	switch format {
+	case ImportCSV, "":
+		reader := csv.NewReader(r)
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if err != nil {
+			return err
+		}
+		header = append([]string(nil), header...)
+
+		for {
+			values, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				err = fn(parseErr.StartLine, "", nil, parseErr)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			line, _ := reader.FieldPos(0)
+
+			record := make(map[string]interface{}, len(header))
+			for i, column := range header {
+				if i < len(values) {
+					record[column] = values[i]
+				}
+			}
+
+			err = fn(line, encodeCSVLine(values), record, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+	case ImportNDJSON:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+		for line := 1; scanner.Scan(); line++ {
+			raw := scanner.Text()
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(raw))
+			decoder.UseNumber()
+
+			var object map[string]interface{}
+			decodeErr := decoder.Decode(&object)
+
+			record := map[string]interface{}{}
+			flattenRecord("", object, record)
+
+			err := fn(line, raw, record, decodeErr)
+			if err != nil {
+				return err
+			}
+		}
+
+		return scanner.Err()
+
+	default:
+		return fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// menulis ulang satu baris csv, agar value yang mengandung koma tetap di quote
+func encodeCSVLine(values []string) string {
+	var builder strings.Builder
+	writer := csv.NewWriter(&builder)
+	writer.Write(values)
+	writer.Flush()
+
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// meratakan object json bersarang, contoh {"Name": {"FirstName": "A"}} => {"Name.FirstName": "A"}
+func flattenRecord(prefix string, object map[string]interface{}, record map[string]interface{}) {
+	for key, value := range object {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch value := value.(type) {
+		case map[string]interface{}:
+			flattenRecord(key, value, record)
+		case json.Number:
+			record[key] = value.String()
+		default:
+			record[key] = value
+		}
+	}
+}
+
+// membaca checkpoint, mengembalikan 0 jika file belum ada
+func readCheckpoint(path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(strings.TrimSpace(string(data)))
+}
+
+// menyimpan checkpoint, ditulis ke file sementara terlebih dahulu agar tidak rusak ketika proses terhenti
+func writeCheckpoint(path string, line int) error {
+	if path == "" {
+		return nil
+	}
+
+	temp := path + ".tmp"
+	err := os.WriteFile(temp, []byte(strconv.Itoa(line)), 0o644)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(temp, path)
+}
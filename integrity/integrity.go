@@ -0,0 +1,292 @@
+// package integrity memeriksa integritas referensial berdasarkan relasi pada schema model gorm
+//
+// pemeriksaan yang dilakukan untuk setiap relasi :
+//   - data child yang parent nya tidak ada (orphan_child), contoh wallet dengan user_id yang tidak ada di tabel users
+//   - data tabel penghubung many to many yang salah satu sisi nya tidak ada (dangling_join)
+//   - relasi has one yang memiliki lebih dari satu child (duplicate_child), contoh user dengan dua wallet
+//   - parent yang sudah di soft delete tetapi child nya masih aktif (soft_deleted_parent)
+//
+// hasil pemeriksaan bisa dijadikan RepairPlan yang dijalankan per batch di dalam transaction
+package integrity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type Kind string
+
+const (
+	OrphanChild       Kind = "orphan_child"
+	DanglingJoin      Kind = "dangling_join"
+	DuplicateChild    Kind = "duplicate_child"
+	SoftDeletedParent Kind = "soft_deleted_parent"
+)
+
+// jumlah contoh nilai foreign key yang dimasukkan ke laporan
+const sampleSize = 10
+
+// satu masalah integritas pada sebuah relasi
+// Table.Column adalah foreign key (pada tabel child atau tabel penghubung) yang mengarah ke ParentTable.ParentColumn
+type Issue struct {
+	Kind            Kind     `json:"kind"`
+	Relation        string   `json:"relation"`
+	Table           string   `json:"table"`
+	Column          string   `json:"column"`
+	ParentTable     string   `json:"parent_table"`
+	ParentColumn    string   `json:"parent_column"`
+	ChildDeletedAt  string   `json:"child_deleted_at,omitempty"`  // kolom soft delete pada child
+	ChildLedger     string   `json:"child_ledger,omitempty"`      // kolom saldo pada child (tag ledger), contoh wallets.balance
+	ParentDeletedAt string   `json:"parent_deleted_at,omitempty"` // kolom soft delete pada parent
+	Count           int64    `json:"count"`                       // jumlah baris, untuk duplicate_child jumlah parent
+	Samples         []string `json:"samples"`                     // contoh nilai foreign key
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s %s: %s.%s -> %s.%s (%d rows, e.g. %s)",
+		i.Kind, i.Relation, i.Table, i.Column, i.ParentTable, i.ParentColumn, i.Count, strings.Join(i.Samples, ", "))
+}
+
+type Report struct {
+	Relations int      `json:"relations"`
+	Skipped   []string `json:"skipped,omitempty"` // relasi yang tabel nya belum ada di database
+	Issues    []Issue  `json:"issues"`
+}
+
+// true jika ada masalah integritas
+func (r Report) HasIssues() bool {
+	return len(r.Issues) > 0
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	for _, issue := range r.Issues {
+		_, err := fmt.Fprintln(w, issue.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d issues in %d relations\n", len(r.Issues), r.Relations)
+	return err
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// satu pemeriksaan, foreign key Table.Column ke ParentTable.ParentColumn
+type check struct {
+	kind     Kind
+	relation string
+	child    *schema.Schema // nil untuk tabel penghubung
+	table    string
+	column   string
+	parent   *schema.Schema
+	pcolumn  string
+}
+
+// memeriksa semua relasi pada model
+func Scan(ctx context.Context, db *gorm.DB, models ...interface{}) (Report, error) {
+	report := Report{Issues: []Issue{}}
+	db = db.WithContext(ctx)
+
+	checks, err := checks(db, models...)
+	if err != nil {
+		return report, err
+	}
+
+	relations := map[string]bool{}
+	skipped := map[string]bool{}
+	migrator := db.Migrator()
+	for _, c := range checks {
+		relations[c.relation] = true
+
+		if !migrator.HasTable(c.table) || !migrator.HasTable(c.parent.Table) {
+			if !skipped[c.relation] {
+				skipped[c.relation] = true
+				report.Skipped = append(report.Skipped, c.relation)
+			}
+			continue
+		}
+
+		issue := Issue{
+			Kind:            c.kind,
+			Relation:        c.relation,
+			Table:           c.table,
+			Column:          c.column,
+			ParentTable:     c.parent.Table,
+			ParentColumn:    c.pcolumn,
+			ParentDeletedAt: deletedAtColumn(c.parent),
+		}
+		if c.child != nil {
+			issue.ChildDeletedAt = deletedAtColumn(c.child)
+			issue.ChildLedger = ledgerColumn(c.child)
+		}
+
+		// relasi parent tanpa soft delete tidak perlu di periksa untuk soft_deleted_parent
+		if issue.Kind == SoftDeletedParent && issue.ParentDeletedAt == "" {
+			continue
+		}
+
+		issue.Count, err = issue.count(db)
+		if err != nil {
+			return report, fmt.Errorf("integrity: %s %s: %w", issue.Kind, issue.Relation, err)
+		}
+		if issue.Count == 0 {
+			continue
+		}
+
+		err = issue.keys(db).Limit(sampleSize).Scan(&issue.Samples).Error
+		if err != nil {
+			return report, fmt.Errorf("integrity: %s %s: %w", issue.Kind, issue.Relation, err)
+		}
+
+		report.Issues = append(report.Issues, issue)
+	}
+	report.Relations = len(relations)
+
+	return report, nil
+}
+
+// membuat daftar pemeriksaan dari relasi pada schema, relasi yang sama dari dua sisi (has many dan belongs to) hanya diperiksa sekali
+func checks(db *gorm.DB, models ...interface{}) ([]check, error) {
+	var result []check
+	seen := map[string]bool{}
+	cache := &sync.Map{}
+
+	add := func(c check) {
+		key := fmt.Sprintf("%s:%s.%s:%s.%s", c.kind, c.table, c.column, c.parent.Table, c.pcolumn)
+		if !seen[key] {
+			seen[key] = true
+			result = append(result, c)
+		}
+	}
+
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(s.Relationships.Relations))
+		for name := range s.Relationships.Relations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			relationship := s.Relationships.Relations[name]
+			label := relationship.Schema.Name + "." + relationship.Name
+
+			for _, reference := range relationship.References {
+				// relasi polymorphic tidak diperiksa
+				if reference.PrimaryKey == nil {
+					continue
+				}
+
+				switch relationship.Type {
+				case schema.HasOne, schema.HasMany:
+					c := check{relation: label, child: relationship.FieldSchema, table: relationship.FieldSchema.Table,
+						column: reference.ForeignKey.DBName, parent: relationship.Schema, pcolumn: reference.PrimaryKey.DBName}
+					for _, kind := range []Kind{OrphanChild, SoftDeletedParent} {
+						c.kind = kind
+						add(c)
+					}
+					if relationship.Type == schema.HasOne {
+						c.kind = DuplicateChild
+						add(c)
+					}
+
+				case schema.BelongsTo:
+					c := check{relation: label, child: relationship.Schema, table: relationship.Schema.Table,
+						column: reference.ForeignKey.DBName, parent: relationship.FieldSchema, pcolumn: reference.PrimaryKey.DBName}
+					for _, kind := range []Kind{OrphanChild, SoftDeletedParent} {
+						c.kind = kind
+						add(c)
+					}
+
+				case schema.Many2Many:
+					parent := relationship.FieldSchema
+					if reference.OwnPrimaryKey {
+						parent = relationship.Schema
+					}
+					add(check{kind: DanglingJoin, relation: label, table: relationship.JoinTable.Table,
+						column: reference.ForeignKey.DBName, parent: parent, pcolumn: reference.PrimaryKey.DBName})
+				}
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func deletedAtColumn(s *schema.Schema) string {
+	for _, field := range s.Fields {
+		if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+			return field.DBName
+		}
+	}
+
+	return ""
+}
+
+// kolom saldo yang ditandai dengan tag ledger, contoh `gorm:"column:balance;ledger"`
+func ledgerColumn(s *schema.Schema) string {
+	for _, field := range s.Fields {
+		if _, ok := field.TagSettings["LEDGER"]; ok && field.DBName != "" {
+			return field.DBName
+		}
+	}
+
+	return ""
+}
+
+// query dari tabel child (alias c) yang memenuhi kondisi masalah, dikelompokkan per nilai foreign key
+func (i Issue) query(db *gorm.DB) *gorm.DB {
+	foreignKey := clause.Column{Table: "c", Name: i.Column}
+	parentKey := clause.Column{Table: "p", Name: i.ParentColumn}
+
+	query := db.Table("? AS c", clause.Table{Name: i.Table}).Where("? IS NOT NULL", foreignKey)
+	if i.ChildDeletedAt != "" {
+		query = query.Where("? IS NULL", clause.Column{Table: "c", Name: i.ChildDeletedAt})
+	}
+
+	switch i.Kind {
+	case OrphanChild, DanglingJoin:
+		query = query.Joins("LEFT JOIN ? AS p ON ? = ?", clause.Table{Name: i.ParentTable}, foreignKey, parentKey).
+			Where("? IS NULL", parentKey)
+	case SoftDeletedParent:
+		query = query.Joins("JOIN ? AS p ON ? = ?", clause.Table{Name: i.ParentTable}, foreignKey, parentKey).
+			Where("? IS NOT NULL", clause.Column{Table: "p", Name: i.ParentDeletedAt})
+	case DuplicateChild:
+		query = query.Group(query.Statement.Quote(foreignKey)).Having("COUNT(*) > 1")
+	}
+
+	return query
+}
+
+// nilai foreign key yang bermasalah
+func (i Issue) keys(db *gorm.DB) *gorm.DB {
+	foreignKey := clause.Column{Table: "c", Name: i.Column}
+	return i.query(db).Select("DISTINCT ?", foreignKey).Order(clause.OrderByColumn{Column: foreignKey})
+}
+
+// jumlah baris bermasalah, untuk duplicate_child jumlah nilai foreign key yang duplikat
+// (Count pada query dengan GROUP BY menghasilkan jumlah group)
+func (i Issue) count(db *gorm.DB) (int64, error) {
+	var count int64
+	err := i.query(db).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,154 @@
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RepairAction string
+
+const (
+	RepairDelete     RepairAction = "delete"      // data child / tabel penghubung di hapus
+	RepairSoftDelete RepairAction = "soft_delete" // data child di soft delete
+	RepairManual     RepairAction = "manual"      // tidak diperbaiki otomatis, butuh keputusan (contoh menggabungkan saldo wallet duplikat)
+)
+
+// langkah perbaikan untuk satu masalah
+type RepairStep struct {
+	Issue  Issue        `json:"issue"`
+	Action RepairAction `json:"action"`
+}
+
+type RepairPlan struct {
+	Steps []RepairStep `json:"steps"`
+}
+
+// hasil perbaikan satu langkah
+type RepairResult struct {
+	Step    RepairStep `json:"step"`
+	Rows    int64      `json:"rows"`
+	Batches int        `json:"batches"`
+}
+
+var ErrNoProgress = errors.New("repair batch did not change any rows")
+
+// membuat rencana perbaikan dari laporan
+//   - orphan_child : child di soft delete jika memiliki kolom soft delete, jika tidak di hapus
+//   - orphan_child pada tabel yang menyimpan saldo (tag ledger, contoh wallets) : di perbaiki manual,-
+//     karena menghapus nya akan mengubah total saldo (contoh wallet milik user yang sudah di hapus)
+//   - dangling_join : data di hapus
+//   - soft_deleted_parent : child di soft delete jika memiliki kolom soft delete, jika tidak di perbaiki manual
+//   - duplicate_child : di perbaiki manual
+func Plan(report Report) RepairPlan {
+	plan := RepairPlan{Steps: []RepairStep{}}
+
+	for _, issue := range report.Issues {
+		action := RepairManual
+		switch issue.Kind {
+		case OrphanChild:
+			if issue.ChildLedger == "" {
+				action = RepairDelete
+				if issue.ChildDeletedAt != "" {
+					action = RepairSoftDelete
+				}
+			}
+		case DanglingJoin:
+			action = RepairDelete
+		case SoftDeletedParent:
+			if issue.ChildDeletedAt != "" {
+				action = RepairSoftDelete
+			}
+		}
+
+		plan.Steps = append(plan.Steps, RepairStep{Issue: issue, Action: action})
+	}
+
+	return plan
+}
+
+func (p RepairPlan) WriteText(w io.Writer) error {
+	for _, step := range p.Steps {
+		_, err := fmt.Fprintf(w, "%s: %s\n", step.Action, step.Issue.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// menjalankan rencana perbaikan, setiap batch (sejumlah nilai foreign key) dijalankan di dalam transaction sendiri
+// sehingga perbaikan data yang besar tidak mengunci tabel terlalu lama, dan bisa dijalankan ulang jika terhenti
+//
+// perbaikan dijalankan langsung pada tabel (tanpa model), sehingga hook dan delete policy (lihat delete_policy.go) tidak dijalankan,-
+// dan delete adalah hapus permanen. child dari data yang di hapus akan menjadi orphan, dan muncul pada Scan berikutnya
+func (p RepairPlan) Execute(ctx context.Context, db *gorm.DB, batchSize int) ([]RepairResult, error) {
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	var results []RepairResult
+	for _, step := range p.Steps {
+		if step.Action == RepairManual {
+			continue
+		}
+
+		result, err := step.execute(db.WithContext(ctx), batchSize)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("integrity: repair %s %s: %w", step.Issue.Kind, step.Issue.Relation, err)
+		}
+	}
+
+	return results, nil
+}
+
+func (s RepairStep) execute(db *gorm.DB, batchSize int) (RepairResult, error) {
+	result := RepairResult{Step: s}
+
+	for {
+		done := false
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var keys []string
+			err := s.Issue.keys(tx).Limit(batchSize).Scan(&keys).Error
+			if err != nil {
+				return err
+			}
+			if len(keys) == 0 {
+				done = true
+				return nil
+			}
+
+			values := make([]interface{}, len(keys))
+			for i, key := range keys {
+				values[i] = key
+			}
+
+			query := tx.Table(s.Issue.Table).Where(clause.IN{Column: clause.Column{Name: s.Issue.Column}, Values: values})
+			if s.Action == RepairSoftDelete {
+				query = query.Where(clause.Eq{Column: clause.Column{Name: s.Issue.ChildDeletedAt}, Value: nil}).
+					UpdateColumn(s.Issue.ChildDeletedAt, tx.NowFunc())
+			} else {
+				query = query.Delete(map[string]interface{}{})
+			}
+			if query.Error != nil {
+				return query.Error
+			}
+			if query.RowsAffected == 0 {
+				return ErrNoProgress
+			}
+
+			result.Rows += query.RowsAffected
+			result.Batches++
+			return nil
+		})
+		if err != nil || done {
+			return result, err
+		}
+	}
+}
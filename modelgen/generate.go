@@ -0,0 +1,377 @@
+// package modelgen membuat struct model gorm dari tabel yang sudah ada di database (reverse engineering)
+//
+// setiap tabel di tulis ke file <tabel>_gen.go yang selalu di tulis ulang ketika generate dijalankan kembali,-
+// sehingga method tambahan harus ditulis di file lain. tabel yang struct nya sudah ditulis manual (bukan di file _gen.go)-
+// tidak akan di generate, dan method TableName() tidak di generate jika sudah ditulis manual
+package modelgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/inflection"
+	"gorm.io/gorm"
+)
+
+const generatedHeader = "// Code generated by modelgen. DO NOT EDIT.\n"
+
+type Options struct {
+	Dir     string   // folder tujuan
+	Package string   // nama package, default package file lain di folder tujuan atau nama folder
+	Tables  []string // jika kosong, semua tabel
+}
+
+type Result struct {
+	Written []string // file yang di tulis
+	Skipped []string // tabel yang struct nya sudah ditulis manual
+}
+
+// membaca database dan menulis file model
+func Generate(db *gorm.DB, opts Options) (Result, error) {
+	tables, err := Introspect(db, opts.Tables...)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Write(tables, opts)
+}
+
+// menulis file model dari hasil introspeksi
+func Write(tables []Table, opts Options) (Result, error) {
+	var result Result
+
+	existing, err := handWritten(opts.Dir)
+	if err != nil {
+		return result, err
+	}
+
+	if opts.Package == "" {
+		opts.Package = existing.pkg
+	}
+	if opts.Package == "" {
+		opts.Package = filepath.Base(opts.Dir)
+	}
+	if !token.IsIdentifier(opts.Package) {
+		return result, fmt.Errorf("modelgen: invalid package name %q", opts.Package)
+	}
+
+	// relasi has many dibuat dari foreign key tabel lain yang mengarah ke tabel ini
+	referencedBy := map[string][]hasMany{}
+	for _, table := range tables {
+		for _, key := range table.ForeignKeys {
+			referencedBy[key.ReferencedTable] = append(referencedBy[key.ReferencedTable], hasMany{table: table.Name, key: key})
+		}
+	}
+
+	for _, table := range tables {
+		name := StructName(table.Name)
+		if existing.types[name] {
+			result.Skipped = append(result.Skipped, table.Name)
+			continue
+		}
+
+		source, err := render(opts.Package, table, referencedBy[table.Name], !existing.methods[name+".TableName"])
+		if err != nil {
+			return result, fmt.Errorf("modelgen: table %s: %w", table.Name, err)
+		}
+
+		path := filepath.Join(opts.Dir, table.Name+"_gen.go")
+		err = os.WriteFile(path, source, 0o644)
+		if err != nil {
+			return result, err
+		}
+		result.Written = append(result.Written, path)
+	}
+
+	return result, nil
+}
+
+type hasMany struct {
+	table string
+	key   ForeignKey
+}
+
+// membuat source code struct untuk satu tabel
+func render(pkg string, table Table, references []hasMany, withTableName bool) ([]byte, error) {
+	name := StructName(table.Name)
+	imports := map[string]bool{}
+	fields := map[string]bool{}
+
+	var body strings.Builder
+	for _, column := range table.Columns {
+		fieldName := FieldName(column.Name)
+		fields[fieldName] = true
+
+		goType, importPath := goType(column)
+		if importPath != "" {
+			imports[importPath] = true
+		}
+
+		fmt.Fprintf(&body, "\t%s %s `gorm:\"%s\"`\n", fieldName, goType, strings.Join(columnTags(column), ";"))
+	}
+
+	// relasi belongs to dari foreign key pada tabel ini
+	keys := append([]ForeignKey(nil), table.ForeignKeys...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Column < keys[j].Column })
+	for _, key := range keys {
+		fieldName := uniqueField(FieldName(strings.TrimSuffix(key.Column, "_id")), fields)
+		fmt.Fprintf(&body, "\n\t// belongs to %s\n", key.ReferencedTable)
+		fmt.Fprintf(&body, "\t%s *%s `gorm:\"foreignKey:%s;references:%s\"`\n", fieldName, StructName(key.ReferencedTable), key.Column, key.ReferencedColumn)
+	}
+
+	// relasi has many dari foreign key tabel lain
+	sort.Slice(references, func(i, j int) bool {
+		return references[i].table+references[i].key.Column < references[j].table+references[j].key.Column
+	})
+	for _, reference := range references {
+		if reference.table == table.Name {
+			continue
+		}
+		fieldName := uniqueField(FieldName(reference.table), fields)
+		fmt.Fprintf(&body, "\n\t// has many %s\n", reference.table)
+		fmt.Fprintf(&body, "\t%s []%s `gorm:\"foreignKey:%s;references:%s\"`\n", fieldName, StructName(reference.table), reference.key.Column, reference.key.ReferencedColumn)
+	}
+
+	var source bytes.Buffer
+	source.WriteString(generatedHeader + "\n")
+	fmt.Fprintf(&source, "package %s\n\n", pkg)
+
+	if len(imports) > 0 {
+		// import standard library dipisah dengan import lain, seperti goimports
+		var standard, others []string
+		for path := range imports {
+			if strings.Contains(path, ".") {
+				others = append(others, path)
+			} else {
+				standard = append(standard, path)
+			}
+		}
+		sort.Strings(standard)
+		sort.Strings(others)
+
+		source.WriteString("import (\n")
+		for _, path := range standard {
+			fmt.Fprintf(&source, "\t%q\n", path)
+		}
+		if len(standard) > 0 && len(others) > 0 {
+			source.WriteString("\n")
+		}
+		for _, path := range others {
+			fmt.Fprintf(&source, "\t%q\n", path)
+		}
+		source.WriteString(")\n\n")
+	}
+
+	fmt.Fprintf(&source, "// %s adalah model untuk tabel %s\n", name, table.Name)
+	fmt.Fprintf(&source, "type %s struct {\n%s}\n", name, body.String())
+
+	if withTableName {
+		fmt.Fprintf(&source, "\n// menentukan nama table\nfunc (%s *%s) TableName() string {\n\treturn %q\n}\n",
+			strings.ToLower(name[:1]), name, table.Name)
+	}
+
+	return format.Source(source.Bytes())
+}
+
+// tag gorm untuk kolom, mengikuti gaya model yang sudah ada (column, primary_key, autoIncrement, autoCreateTime)
+func columnTags(column Column) []string {
+	tags := []string{"column:" + column.Name}
+
+	if column.PrimaryKey {
+		tags = append(tags, "primary_key")
+	}
+	if column.AutoIncrement {
+		tags = append(tags, "autoIncrement")
+	}
+	if column.Length > 0 {
+		tags = append(tags, fmt.Sprintf("size:%d", column.Length))
+	}
+
+	milli := ""
+	if isIntegerType(column.DatabaseType) {
+		milli = ":milli"
+	}
+
+	switch column.Name {
+	case "created_at":
+		tags = append(tags, "autoCreateTime"+milli)
+	case "updated_at":
+		tags = append(tags, "autoCreateTime"+milli, "autoUpdateTime"+milli)
+	}
+
+	if column.DatabaseType == "JSON" || column.DatabaseType == "JSONB" {
+		tags = append(tags, "type:"+strings.ToLower(column.DatabaseType))
+	}
+
+	return tags
+}
+
+// tipe go untuk kolom, kolom nullable (selain primary key) menggunakan pointer
+func goType(column Column) (string, string) {
+	var goType, importPath string
+
+	databaseType := column.DatabaseType
+	switch {
+	case column.Name == "deleted_at" && isTimeType(databaseType):
+		return "gorm.DeletedAt", "gorm.io/gorm"
+	case databaseType == "JSON" || databaseType == "JSONB":
+		return "json.RawMessage", "encoding/json"
+	case strings.Contains(databaseType, "BLOB") || strings.Contains(databaseType, "BINARY") || databaseType == "BYTEA":
+		return "[]byte", ""
+	case databaseType == "BOOL" || databaseType == "BOOLEAN" || databaseType == "BIT":
+		goType = "bool"
+	case isIntegerType(databaseType):
+		goType = integerTypes[databaseType]
+		if column.Unsigned {
+			goType = "u" + goType
+		}
+	case databaseType == "DECIMAL" || databaseType == "NUMERIC" || databaseType == "FLOAT" ||
+		databaseType == "DOUBLE" || databaseType == "REAL" || strings.HasPrefix(databaseType, "FLOAT") ||
+		databaseType == "DOUBLE PRECISION":
+		goType = "float64"
+	case isTimeType(databaseType):
+		goType, importPath = "time.Time", "time"
+	default:
+		goType = "string"
+	}
+
+	if column.Nullable && !column.PrimaryKey {
+		goType = "*" + goType
+	}
+
+	return goType, importPath
+}
+
+// tipe integer berdasarkan nama tipe yang utuh (bukan substring, INTERVAL dan POINT bukan integer)
+// INTEGER pada sqlite adalah 64 bit dan dibuat oleh gorm untuk field int64, sehingga dipetakan ke int64
+var integerTypes = map[string]string{
+	"TINYINT":     "int8",
+	"SMALLINT":    "int16",
+	"INT2":        "int16",
+	"SMALLSERIAL": "int16",
+	"MEDIUMINT":   "int32",
+	"INT":         "int32",
+	"INT4":        "int32",
+	"SERIAL":      "int32",
+	"INTEGER":     "int64",
+	"BIGINT":      "int64",
+	"INT8":        "int64",
+	"BIGSERIAL":   "int64",
+}
+
+func isIntegerType(databaseType string) bool {
+	_, ok := integerTypes[databaseType]
+	return ok
+}
+
+func isTimeType(databaseType string) bool {
+	return strings.Contains(databaseType, "DATE") || strings.Contains(databaseType, "TIME")
+}
+
+// nama struct dari nama tabel, contoh guest_books => GuestBook
+func StructName(table string) string {
+	return FieldName(inflection.Singular(table))
+}
+
+// nama field dari nama kolom, mengikuti gaya model yang sudah ada : id => ID, user_id => UserId
+func FieldName(column string) string {
+	if column == "id" {
+		return "ID"
+	}
+
+	var builder strings.Builder
+	for _, part := range strings.Split(column, "_") {
+		if part == "" {
+			continue
+		}
+		builder.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	return builder.String()
+}
+
+func uniqueField(name string, fields map[string]bool) string {
+	candidate := name
+	for i := 2; fields[candidate]; i++ {
+		candidate = fmt.Sprintf("%s%d", name, i)
+	}
+	fields[candidate] = true
+
+	return candidate
+}
+
+type declarations struct {
+	pkg     string
+	types   map[string]bool
+	methods map[string]bool // nama type + "." + nama method
+}
+
+// membaca nama package, serta type dan method yang ditulis manual (file .go selain _gen.go dan _test.go)
+func handWritten(dir string) (declarations, error) {
+	result := declarations{types: map[string]bool{}, methods: map[string]bool{}}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return result, err
+	}
+
+	fileSet := token.NewFileSet()
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(fileSet, path, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return result, err
+		}
+
+		if result.pkg == "" {
+			result.pkg = file.Name.Name
+		}
+		if strings.HasSuffix(path, "_gen.go") {
+			continue
+		}
+
+		for _, declaration := range file.Decls {
+			switch declaration := declaration.(type) {
+			case *ast.GenDecl:
+				for _, spec := range declaration.Specs {
+					if typeSpec, ok := spec.(*ast.TypeSpec); ok {
+						result.types[typeSpec.Name.Name] = true
+					}
+				}
+			case *ast.FuncDecl:
+				if declaration.Recv == nil || len(declaration.Recv.List) == 0 {
+					continue
+				}
+				result.methods[receiverName(declaration.Recv.List[0].Type)+"."+declaration.Name.Name] = true
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func receiverName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	case *ast.IndexExpr:
+		return receiverName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverName(expr.X)
+	}
+
+	return ""
+}
@@ -0,0 +1,122 @@
+package modelgen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// kolom hasil introspeksi database
+type Column struct {
+	Name          string
+	DatabaseType  string // contoh VARCHAR, BIGINT
+	Length        int64  // ukuran kolom untuk varchar / char, 0 jika tidak ada
+	PrimaryKey    bool
+	AutoIncrement bool
+	Nullable      bool
+	Unsigned      bool // integer unsigned (mysql), dipetakan ke uint8 / uint16 / uint32 / uint64
+}
+
+// foreign key, Column pada tabel ini mengarah ke ReferencedTable.ReferencedColumn
+type ForeignKey struct {
+	Column           string
+	ReferencedTable  string
+	ReferencedColumn string
+}
+
+type Table struct {
+	Name        string
+	Columns     []Column
+	ForeignKeys []ForeignKey
+}
+
+// membaca tabel, kolom dan foreign key dari database
+// kolom dibaca melalui Migrator (ColumnTypes), foreign key dibaca dari information_schema (mysql, postgres)-
+// atau PRAGMA foreign_key_list (sqlite)
+func Introspect(db *gorm.DB, tables ...string) ([]Table, error) {
+	migrator := db.Migrator()
+
+	if len(tables) == 0 {
+		var err error
+		tables, err = migrator.GetTables()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(tables)
+
+	result := make([]Table, 0, len(tables))
+	for _, name := range tables {
+		columnTypes, err := migrator.ColumnTypes(name)
+		if err != nil {
+			return nil, fmt.Errorf("modelgen: table %s: %w", name, err)
+		}
+
+		table := Table{Name: name}
+		for _, columnType := range columnTypes {
+			column := Column{Name: columnType.Name(), DatabaseType: strings.ToUpper(columnType.DatabaseTypeName())}
+			column.PrimaryKey, _ = columnType.PrimaryKey()
+			column.AutoIncrement, _ = columnType.AutoIncrement()
+			column.Nullable, _ = columnType.Nullable()
+			if definition, ok := columnType.ColumnType(); ok {
+				column.Unsigned = strings.Contains(strings.ToUpper(definition), "UNSIGNED")
+			}
+			if length, ok := columnType.Length(); ok && isStringType(column.DatabaseType) {
+				column.Length = length
+			}
+			table.Columns = append(table.Columns, column)
+		}
+
+		table.ForeignKeys, err = foreignKeys(db, name)
+		if err != nil {
+			return nil, fmt.Errorf("modelgen: table %s: %w", name, err)
+		}
+
+		result = append(result, table)
+	}
+
+	return result, nil
+}
+
+func foreignKeys(db *gorm.DB, table string) ([]ForeignKey, error) {
+	var keys []ForeignKey
+
+	switch db.Dialector.Name() {
+	case "mysql":
+		err := db.Raw("SELECT COLUMN_NAME AS `column`, REFERENCED_TABLE_NAME AS referenced_table, REFERENCED_COLUMN_NAME AS referenced_column "+
+			"FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? "+
+			"AND REFERENCED_TABLE_NAME IS NOT NULL ORDER BY COLUMN_NAME", table).Scan(&keys).Error
+		return keys, err
+
+	case "postgres":
+		err := db.Raw(`SELECT kcu.column_name AS "column", ccu.table_name AS referenced_table, ccu.column_name AS referenced_column
+			FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu ON tc.constraint_name = kcu.constraint_name AND tc.table_schema = kcu.table_schema
+			JOIN information_schema.constraint_column_usage ccu ON tc.constraint_name = ccu.constraint_name AND tc.table_schema = ccu.table_schema
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = CURRENT_SCHEMA() AND tc.table_name = ?
+			ORDER BY kcu.column_name`, table).Scan(&keys).Error
+		return keys, err
+
+	case "sqlite":
+		var rows []struct {
+			From  string `gorm:"column:from"`
+			Table string `gorm:"column:table"`
+			To    string `gorm:"column:to"`
+		}
+		err := db.Raw("SELECT * FROM pragma_foreign_key_list(?)", table).Scan(&rows).Error
+		for _, row := range rows {
+			keys = append(keys, ForeignKey{Column: row.From, ReferencedTable: row.Table, ReferencedColumn: row.To})
+		}
+		return keys, err
+
+	default:
+		// dialect lain : relasi tidak di generate
+		return nil, nil
+	}
+}
+
+func isStringType(databaseType string) bool {
+	return strings.Contains(databaseType, "CHAR")
+}
@@ -0,0 +1,25 @@
+package belajar_go_lang_gorm
+
+// dokumentasi schema di generate dari model, jalankan go generate setelah mengubah model
+//go:generate go run ./cmd/erdgen -format mermaid -out docs/erd.mmd
+//go:generate go run ./cmd/erdgen -format dot -out docs/erd.dot
+//go:generate go run ./cmd/erdgen -format markdown -out docs/data-dictionary.md
+
+// daftar semua model pada project ini
+// digunakan oleh tool yang membutuhkan seluruh schema, contoh cmd/schemadiff dan cmd/erdgen
+// model baru harus ditambahkan ke sini
+func Models() []interface{} {
+	return []interface{}{
+		&User{},
+		&UserLog{},
+		&Todo{},
+		&Wallet{},
+		&Address{},
+		&Product{},
+		&GuestBook{},
+		&Role{},
+		&Permission{},
+		&OutboxEvent{},
+		&ErasureAudit{},
+	}
+}
@@ -0,0 +1,278 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"belajar-go-lang-gorm/explain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi deteksi N+1 query
+// plugin ini mencatat fingerprint setiap query select yang dijalankan di dalam QueryScope (per request atau per test),-
+// query dengan bentuk yang sama yang dijalankan berulang kali (contoh membaca Wallet atau Addresses di dalam loop users) dilaporkan sebagai N+1
+// query tanpa QueryScope pada context tidak dicatat
+// contoh : db.Use(NewNPlusOneDetector(NPlusOneConfig{Threshold: 2, OnDetect: ...}))
+type NPlusOneDetector struct {
+	config NPlusOneConfig
+}
+
+type NPlusOneConfig struct {
+	Threshold int                                                // jumlah maksimal query dengan bentuk yang sama per scope, default 2
+	OnDetect  func(scope string, detections []NPlusOneDetection) // dipanggil oleh Middleware jika ada N+1 pada request
+}
+
+// satu pola N+1, query dengan fingerprint yang sama yang dijalankan Count kali
+type NPlusOneDetection struct {
+	Table       string
+	Fingerprint string
+	Count       int
+	CallSites   []string // lokasi kode yang menjalankan query (file:line), tanpa duplikat
+	Relation    string   // relasi yang dibaca, contoh User.Wallet (kosong jika tidak diketahui)
+	Suggestion  string   // contoh Joins("Wallet") atau Preload("Addresses")
+}
+
+func (d NPlusOneDetection) String() string {
+	message := fmt.Sprintf("N+1 query on %s (%d times) at %s: %s", d.Table, d.Count, strings.Join(d.CallSites, ", "), d.Fingerprint)
+	if d.Suggestion != "" {
+		message += fmt.Sprintf(", use %s for %s", d.Suggestion, d.Relation)
+	}
+
+	return message
+}
+
+func NewNPlusOneDetector(config NPlusOneConfig) *NPlusOneDetector {
+	if config.Threshold <= 0 {
+		config.Threshold = 2
+	}
+
+	return &NPlusOneDetector{config: config}
+}
+
+func (d *NPlusOneDetector) Name() string {
+	return "n_plus_one"
+}
+
+func (d *NPlusOneDetector) Initialize(db *gorm.DB) error {
+	return db.Callback().Query().After("gorm:query").Register("n_plus_one:after_query", d.after)
+}
+
+func (d *NPlusOneDetector) after(db *gorm.DB) {
+	scope, ok := QueryScopeFromContext(db.Statement.Context)
+	if !ok || db.DryRun || db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	scope.record(db.Statement, callSite())
+}
+
+// lokasi kode (file:line) diluar gorm yang menjalankan query
+// utils.FileWithLineNum tidak digunakan karena akan mengembalikan lokasi callback ini
+func callSite() string {
+	pcs := [32]uintptr{}
+	// melewati runtime.Callers, callSite dan callback after
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// membuka QueryScope untuk setiap request, N+1 yang ditemukan dikirim ke OnDetect setelah request selesai
+// handler harus menjalankan query dengan db.WithContext(r.Context())
+func (d *NPlusOneDetector) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, scope := ContextWithQueryScope(r.Context(), r.Method+" "+r.URL.Path)
+		next.ServeHTTP(w, r.WithContext(ctx))
+
+		detections := scope.Detections(d.config.Threshold)
+		if len(detections) > 0 && d.config.OnDetect != nil {
+			d.config.OnDetect(scope.Name(), detections)
+		}
+	})
+}
+
+// kumpulan query yang dijalankan dalam satu request atau satu test
+type QueryScope struct {
+	name    string
+	mu      sync.Mutex
+	queries map[string]*scopedQuery
+	order   []string
+	schemas []*schema.Schema // schema model yang di query, untuk mencari relasi
+}
+
+type scopedQuery struct {
+	table     string
+	schema    *schema.Schema
+	count     int
+	callSites []string
+}
+
+type queryScopeKey struct{}
+
+// membuat QueryScope baru dan menyimpan nya ke dalam context
+// contoh : ctx, scope := ContextWithQueryScope(ctx, t.Name()); db.WithContext(ctx).Find(&users)
+func ContextWithQueryScope(ctx context.Context, name string) (context.Context, *QueryScope) {
+	scope := &QueryScope{name: name, queries: map[string]*scopedQuery{}}
+	return context.WithValue(ctx, queryScopeKey{}, scope), scope
+}
+
+// mengambil QueryScope dari context
+func QueryScopeFromContext(ctx context.Context) (*QueryScope, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+
+	scope, ok := ctx.Value(queryScopeKey{}).(*QueryScope)
+	return scope, ok
+}
+
+func (s *QueryScope) Name() string {
+	return s.name
+}
+
+func (s *QueryScope) record(statement *gorm.Statement, callSite string) {
+	fingerprint := FingerprintSQL(statement.SQL.String())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query, ok := s.queries[fingerprint]
+	if !ok {
+		query = &scopedQuery{table: statement.Table, schema: statement.Schema}
+		s.queries[fingerprint] = query
+		s.order = append(s.order, fingerprint)
+	}
+	query.count++
+
+	if !containsString(query.callSites, callSite) {
+		query.callSites = append(query.callSites, callSite)
+	}
+
+	if statement.Schema != nil && !containsSchema(s.schemas, statement.Schema) {
+		s.schemas = append(s.schemas, statement.Schema)
+	}
+}
+
+// query dengan bentuk yang sama yang dijalankan lebih dari threshold kali, diurutkan dari yang paling sering
+func (s *QueryScope) Detections(threshold int) []NPlusOneDetection {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var detections []NPlusOneDetection
+	for _, fingerprint := range s.order {
+		query := s.queries[fingerprint]
+		if query.count <= threshold {
+			continue
+		}
+
+		detection := NPlusOneDetection{
+			Table:       query.table,
+			Fingerprint: fingerprint,
+			Count:       query.count,
+			CallSites:   append([]string(nil), query.callSites...),
+		}
+		detection.Relation, detection.Suggestion = s.suggest(query, fingerprint)
+		detections = append(detections, detection)
+	}
+
+	sort.SliceStable(detections, func(i, j int) bool {
+		return detections[i].Count > detections[j].Count
+	})
+
+	return detections
+}
+
+// menggagalkan test jika ada query dengan bentuk yang sama yang dijalankan lebih dari threshold kali
+// t sama seperti pada explain.AssertEfficient (bagian dari *testing.T)
+func (s *QueryScope) Assert(t explain.TestingT, threshold int) {
+	t.Helper()
+
+	for _, detection := range s.Detections(threshold) {
+		t.Errorf("%s", detection)
+	}
+}
+
+// mencari relasi dari model lain di dalam scope yang mengarah ke tabel query,-
+// has one dan belongs to disarankan menggunakan Joins, has many dan many to many menggunakan Preload
+func (s *QueryScope) suggest(query *scopedQuery, fingerprint string) (string, string) {
+	for _, parent := range s.schemas {
+		names := make([]string, 0, len(parent.Relationships.Relations))
+		for name := range parent.Relationships.Relations {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			relationship := parent.Relationships.Relations[name]
+			if relationship.FieldSchema.Table != query.table || parent == query.schema {
+				continue
+			}
+			if !referencedBy(relationship, fingerprint) {
+				continue
+			}
+
+			label := parent.Name + "." + relationship.Name
+			switch relationship.Type {
+			case schema.HasOne, schema.BelongsTo:
+				return label, fmt.Sprintf("Joins(%q)", relationship.Name)
+			default:
+				return label, fmt.Sprintf("Preload(%q)", relationship.Name)
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// true jika query menggunakan kolom relasi, foreign key pada child atau tabel penghubung untuk many to many
+func referencedBy(relationship *schema.Relationship, fingerprint string) bool {
+	if relationship.Type == schema.Many2Many {
+		return strings.Contains(fingerprint, relationship.JoinTable.Table)
+	}
+
+	for _, reference := range relationship.References {
+		column := reference.ForeignKey.DBName
+		if relationship.Type == schema.BelongsTo && reference.PrimaryKey != nil {
+			column = reference.PrimaryKey.DBName
+		}
+		if strings.Contains(fingerprint, column) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsSchema(schemas []*schema.Schema, s *schema.Schema) bool {
+	for _, v := range schemas {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,286 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// implementasi transactional outbox
+// event domain (contoh : user dibuat, wallet di debit) di simpan ke tabel outbox_events di dalam-
+// transaction yang sama dengan perubahan data nya, sehingga event tidak akan hilang ataupun terkirim-
+// ketika transaction di rollback. event kemudian dikirim oleh relay worker ke Publisher
+type OutboxEvent struct {
+	ID            int64      `gorm:"primary_key;column:id;autoIncrement"`
+	AggregateType string     `gorm:"column:aggregate_type;size:100;index:idx_outbox_aggregate"`
+	AggregateID   string     `gorm:"column:aggregate_id;size:100;index:idx_outbox_aggregate"`
+	EventType     string     `gorm:"column:event_type;size:100"`
+	Payload       string     `gorm:"column:payload;type:text"`
+	Attempts      int        `gorm:"column:attempts"`
+	LastError     string     `gorm:"column:last_error;type:text"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime"`
+	PublishedAt   *time.Time `gorm:"column:published_at;index"`
+	ClaimedUntil  *time.Time `gorm:"column:claimed_until"` // event sedang dikirim oleh relay sampai waktu ini
+}
+
+// menentukan nama table
+func (e OutboxEvent) TableName() string {
+	return "outbox_events"
+}
+
+// jenis event yang dikirim
+const (
+	EventUserCreated   = "user.created"
+	EventWalletDebited = "wallet.debited"
+)
+
+var ErrInsufficientBalance = errors.New("insufficient wallet balance")
+
+// menyimpan event ke tabel outbox, tx harus merupakan transaction yang sama dengan perubahan data nya
+func RecordEvent(tx *gorm.DB, aggregateType string, aggregateID string, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       string(data),
+	}).Error
+}
+
+// membuat user baru sekaligus mencatat event user.created dalam satu transaction
+func CreateUserWithEvent(ctx context.Context, db *gorm.DB, user *User) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(user).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordEvent(tx, "user", user.ID, EventUserCreated, map[string]interface{}{
+			"id":         user.ID,
+			"first_name": user.Name.FirstName,
+			"last_name":  user.Name.LastName,
+		})
+	})
+}
+
+// mengurangi balance wallet sekaligus mencatat event wallet.debited dalam satu transaction
+// data wallet di lock terlebih dahulu agar tidak terjadi race condition ketika debit bersamaan
+func DebitWallet(ctx context.Context, db *gorm.DB, walletID string, amount int64) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var wallet Wallet
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Take(&wallet, "id = ?", walletID).Error
+		if err != nil {
+			return err
+		}
+
+		if wallet.Balance < amount {
+			return ErrInsufficientBalance
+		}
+
+		err = tx.Model(&wallet).Update("balance", wallet.Balance-amount).Error
+		if err != nil {
+			return err
+		}
+
+		return RecordEvent(tx, "wallet", wallet.ID, EventWalletDebited, map[string]interface{}{
+			"wallet_id": wallet.ID,
+			"user_id":   wallet.UserId,
+			"amount":    amount,
+			"balance":   wallet.Balance,
+		})
+	})
+}
+
+// tujuan pengiriman event (message broker, webhook, dsb)
+type Publisher interface {
+	Publish(ctx context.Context, event OutboxEvent) error
+}
+
+// adapter agar function biasa bisa digunakan sebagai Publisher
+type PublisherFunc func(ctx context.Context, event OutboxEvent) error
+
+func (f PublisherFunc) Publish(ctx context.Context, event OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// relay worker yang mengambil event dari tabel outbox dan mengirimkan nya ke publisher
+// pengiriman bersifat at-least-once, event yang gagal dikirim akan dicoba lagi pada polling berikutnya
+// urutan event per aggregate di jaga, event berikutnya tidak akan dikirim sebelum event sebelum nya terkirim
+type OutboxRelay struct {
+	db           *gorm.DB
+	publisher    Publisher
+	batchSize    int
+	pollInterval time.Duration
+}
+
+// lama event di klaim oleh satu relay, jika relay berhenti di tengah pengiriman,-
+// event bisa di klaim relay lain setelah waktu ini lewat (dan mungkin terkirim dua kali)
+const OutboxClaimTimeout = 5 * time.Minute
+
+// membuat relay worker baru
+func NewOutboxRelay(db *gorm.DB, publisher Publisher, batchSize int, pollInterval time.Duration) *OutboxRelay {
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	return &OutboxRelay{
+		db:           db,
+		publisher:    publisher,
+		batchSize:    batchSize,
+		pollInterval: pollInterval,
+	}
+}
+
+// menjalankan relay terus menerus sampai context selesai
+func (r *OutboxRelay) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		_, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil {
+			r.db.Logger.Error(ctx, "outbox relay: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// mengambil satu batch event yang belum terkirim, kemudian mengirimkan nya ke publisher
+// mengembalikan jumlah event yang berhasil dikirim
+// event di klaim terlebih dahulu di dalam transaction singkat, pengiriman ke publisher dilakukan di luar transaction-
+// sehingga row lock tidak ditahan selama publisher mengirim event
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	db := r.db.WithContext(ctx)
+
+	events, err := r.claim(db)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	failed := map[string]bool{}
+	for _, event := range events {
+		aggregate := event.AggregateType + ":" + event.AggregateID
+
+		// event setelah event yang gagal pada aggregate yang sama tidak dikirim, agar urutan nya tetap terjaga
+		if failed[aggregate] {
+			err = db.Model(&event).Update("claimed_until", nil).Error
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		publishErr := r.publisher.Publish(ctx, event)
+		if publishErr != nil {
+			// mencatat percobaan yang gagal, event akan dikirim ulang pada polling berikutnya
+			failed[aggregate] = true
+			err = db.Model(&event).Updates(map[string]interface{}{
+				"attempts":      gorm.Expr("attempts + 1"),
+				"last_error":    publishErr.Error(),
+				"claimed_until": nil,
+			}).Error
+			if err != nil {
+				return published, err
+			}
+			continue
+		}
+
+		now := db.NowFunc()
+		err = db.Model(&event).Updates(map[string]interface{}{
+			"attempts":      gorm.Expr("attempts + 1"),
+			"published_at":  &now,
+			"claimed_until": nil,
+		}).Error
+		if err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, nil
+}
+
+// mengklaim event yang belum terkirim, diurutkan per aggregate
+// satu aggregate bisa memiliki beberapa event dalam satu batch, namun hanya jika event paling awal-
+// yang belum terkirim dari aggregate tersebut ikut terklaim (tidak sedang di klaim relay lain)
+func (r *OutboxRelay) claim(db *gorm.DB) ([]OutboxEvent, error) {
+	var claimed []OutboxEvent
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := tx.NowFunc()
+		query := tx.Where("published_at IS NULL").
+			Where("claimed_until IS NULL OR claimed_until < ?", now).
+			Order("id").
+			Limit(r.batchSize)
+
+		// FOR UPDATE SKIP LOCKED agar event yang sedang di klaim relay lain dilewati
+		// sqlite tidak mendukung row locking (satu writer untuk seluruh database), sehingga tidak digunakan
+		if tx.Dialector.Name() != "sqlite" {
+			query = query.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+		}
+
+		var events []OutboxEvent
+		err := query.Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		// event paling awal yang belum terkirim untuk setiap aggregate di batch ini
+		aggregateIDs := []string{}
+		for _, event := range events {
+			aggregateIDs = append(aggregateIDs, event.AggregateID)
+		}
+		var earliest []struct {
+			AggregateType string
+			AggregateID   string
+			ID            int64
+		}
+		err = tx.Model(&OutboxEvent{}).
+			Select("aggregate_type, aggregate_id, MIN(id) AS id").
+			Where("published_at IS NULL").
+			Where("aggregate_id IN ?", aggregateIDs).
+			Group("aggregate_type, aggregate_id").
+			Scan(&earliest).Error
+		if err != nil {
+			return err
+		}
+		first := map[string]int64{}
+		for _, row := range earliest {
+			first[row.AggregateType+":"+row.AggregateID] = row.ID
+		}
+
+		// aggregate yang event paling awal nya tidak ada di batch (sedang di klaim relay lain) dilewati
+		ids := []int64{}
+		started := map[string]bool{}
+		for _, event := range events {
+			aggregate := event.AggregateType + ":" + event.AggregateID
+			if !started[aggregate] && first[aggregate] != event.ID {
+				continue
+			}
+			started[aggregate] = true
+			claimed = append(claimed, event)
+			ids = append(ids, event.ID)
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		return tx.Model(&OutboxEvent{}).Where("id IN ?", ids).Update("claimed_until", now.Add(OutboxClaimTimeout)).Error
+	})
+
+	return claimed, err
+}
@@ -1,6 +1,9 @@
 package belajar_go_lang_gorm
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type Product struct {
 	ID        string `gorm:"primary_key;column:id"`
@@ -20,6 +23,23 @@ type Product struct {
 	LikedByUsers []User `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:product_id;references:id;joinReferences:user_id"`
 }
 
+// implementasi validasi, digunakan oleh importer sebelum data di simpan
+func (p *Product) Validate() error {
+	if p.ID == "" {
+		return errors.New("id is required")
+	}
+
+	if p.Name == "" {
+		return errors.New("name is required")
+	}
+
+	if p.Price < 0 {
+		return errors.New("price must not be negative")
+	}
+
+	return nil
+}
+
 // menentukan nama table
 func (w Product) TableName() string {
 	return "products"
@@ -0,0 +1,121 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// implementasi pengecekan hak akses (authorizer)
+// daftar permission setiap user di simpan di memory (cache) selama ttl,
+// sehingga tidak perlu melakukan query join ke tabel role setiap kali melakukan pengecekan
+type Authorizer struct {
+	db  *gorm.DB
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]cachedPermissions
+}
+
+type cachedPermissions struct {
+	names     map[string]bool
+	expiredAt time.Time
+}
+
+// membuat authorizer baru, ttl menentukan berapa lama cache permission user berlaku
+func NewAuthorizer(db *gorm.DB, ttl time.Duration) *Authorizer {
+	return &Authorizer{
+		db:    db,
+		ttl:   ttl,
+		cache: map[string]cachedPermissions{},
+	}
+}
+
+// mengecek apakah user boleh melakukan action terhadap resource
+// contoh : Can(ctx, "1", "read:any", "todo") akan mengecek permission 'todo:read:any'
+func (a *Authorizer) Can(ctx context.Context, userID string, action string, resource string) (bool, error) {
+	permissions, err := a.permissions(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return permissions[resource+":"+action], nil
+}
+
+// menghapus cache permission milik user, panggil setelah role atau permission user berubah
+func (a *Authorizer) Invalidate(userID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.cache, userID)
+}
+
+// menghapus seluruh cache permission, panggil setelah permission sebuah role berubah
+func (a *Authorizer) InvalidateAll() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.cache = map[string]cachedPermissions{}
+}
+
+// mengambil daftar permission user dari cache, jika belum ada atau sudah kadaluarsa maka query ke database
+func (a *Authorizer) permissions(ctx context.Context, userID string) (map[string]bool, error) {
+	a.mu.RLock()
+	cached, ok := a.cache[userID]
+	a.mu.RUnlock()
+
+	if ok && time.Now().Before(cached.expiredAt) {
+		return cached.names, nil
+	}
+
+	// mengambil nama permission dari seluruh role yang dimiliki user
+	var names []string
+	err := a.db.WithContext(ctx).Model(&Permission{}).
+		Distinct("permissions.name").
+		Joins("join role_permissions on role_permissions.permission_id = permissions.id").
+		Joins("join user_roles on user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Pluck("permissions.name", &names).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(names))
+	for _, name := range names {
+		permissions[name] = true
+	}
+
+	a.mu.Lock()
+	a.cache[userID] = cachedPermissions{names: permissions, expiredAt: time.Now().Add(a.ttl)}
+	a.mu.Unlock()
+
+	return permissions, nil
+}
+
+// implementasi scopes untuk membatasi data sesuai hak akses
+// jika user memiliki permission '<resource>:read:any' maka semua data boleh dilihat,
+// selain itu hanya data dengan kolom user_id milik user itu sendiri
+func (a *Authorizer) VisibleTo(ctx context.Context, userID string, resource string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		readAny, err := a.Can(ctx, userID, "read:any", resource)
+		if err != nil {
+			// error pada scopes di kembalikan melalui db.Error
+			db.AddError(err)
+			return db
+		}
+
+		if readAny {
+			return db
+		}
+
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// scopes khusus untuk model Todo
+func (a *Authorizer) VisibleTodos(ctx context.Context, userID string) func(db *gorm.DB) *gorm.DB {
+	return a.VisibleTo(ctx, userID, "todo")
+}
@@ -0,0 +1,162 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// implementasi generic repository menggunakan generics api gorm (gorm.G)
+// T adalah model, ID adalah tipe primary key, contoh Repository[User, string] atau Repository[Todo, uint]
+// data yang tidak ditemukan mengembalikan gorm.ErrRecordNotFound (termasuk pada repository in-memory)
+type Repository[T any, ID comparable] interface {
+	Get(ctx context.Context, id ID) (T, error)
+	List(ctx context.Context, opts ListOptions) ([]T, error)
+	Create(ctx context.Context, value *T) error
+
+	// update data berdasarkan id, fields adalah field mask (nama field atau kolom) yang di update
+	// jika fields kosong, hanya field yang tidak bernilai zero yang di update (sama seperti db.Updates)
+	// data yang tidak ditemukan mengembalikan gorm.ErrRecordNotFound, sama seperti Delete
+	Update(ctx context.Context, id ID, value T, fields ...string) error
+
+	Delete(ctx context.Context, id ID) error
+	Exists(ctx context.Context, id ID) (bool, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+// pengaturan List
+type ListOptions struct {
+	Limit   int // jika 0, tanpa limit
+	Offset  int
+	OrderBy string // nama kolom, default primary key
+	Desc    bool
+}
+
+// repository yang menggunakan database
+type GormRepository[T any, ID comparable] struct {
+	db *gorm.DB
+}
+
+func NewRepository[T any, ID comparable](db *gorm.DB) *GormRepository[T, ID] {
+	return &GormRepository[T, ID]{db: db}
+}
+
+// kondisi primary key = id, clause.PrimaryColumn otomatis menggunakan primary key model
+func byID[ID comparable](id ID) clause.Expression {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
+
+func (r *GormRepository[T, ID]) Get(ctx context.Context, id ID) (T, error) {
+	return gorm.G[T](r.db).Where(byID(id)).Take(ctx)
+}
+
+func (r *GormRepository[T, ID]) List(ctx context.Context, opts ListOptions) ([]T, error) {
+	order := clause.OrderByColumn{Column: clause.PrimaryColumn, Desc: opts.Desc}
+	if opts.OrderBy != "" {
+		order.Column = clause.Column{Table: clause.CurrentTable, Name: opts.OrderBy}
+	}
+
+	query := gorm.G[T](r.db).Order(order)
+	if opts.Limit > 0 {
+		query = query.Limit(opts.Limit)
+	}
+	if opts.Offset > 0 {
+		query = query.Offset(opts.Offset)
+	}
+
+	return query.Find(ctx)
+}
+
+func (r *GormRepository[T, ID]) Create(ctx context.Context, value *T) error {
+	return gorm.G[T](r.db).Create(ctx, value)
+}
+
+func (r *GormRepository[T, ID]) Update(ctx context.Context, id ID, value T, fields ...string) error {
+	query := gorm.G[T](r.db).Where(byID(id))
+	if len(fields) > 0 {
+		others := make([]interface{}, len(fields)-1)
+		for i, field := range fields[1:] {
+			others[i] = field
+		}
+		query = query.Select(fields[0], others...)
+	}
+
+	rows, err := query.Updates(ctx, value)
+	if err != nil || rows > 0 {
+		return err
+	}
+
+	// mysql mengembalikan 0 baris jika value nya sama dengan data lama, sehingga dicek terlebih dahulu apakah data nya ada
+	exists, err := r.Exists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GormRepository[T, ID]) Delete(ctx context.Context, id ID) error {
+	rows, err := gorm.G[T](r.db).Where(byID(id)).Delete(ctx)
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (r *GormRepository[T, ID]) Exists(ctx context.Context, id ID) (bool, error) {
+	count, err := gorm.G[T](r.db).Where(byID(id)).Count(ctx, "*")
+	return count > 0, err
+}
+
+func (r *GormRepository[T, ID]) Count(ctx context.Context) (int64, error) {
+	return gorm.G[T](r.db).Count(ctx, "*")
+}
+
+// repository user
+type UserRepository interface {
+	Repository[User, string]
+
+	// mengambil user beserta relasi Wallet dan Addresses
+	WithWalletAndAddresses(ctx context.Context, id string) (User, error)
+}
+
+type GormUserRepository struct {
+	*GormRepository[User, string]
+}
+
+func NewUserRepository(db *gorm.DB) *GormUserRepository {
+	return &GormUserRepository{GormRepository: NewRepository[User, string](db)}
+}
+
+func (r *GormUserRepository) WithWalletAndAddresses(ctx context.Context, id string) (User, error) {
+	return gorm.G[User](r.db).Preload("Wallet", nil).Preload("Addresses", nil).Where(byID(id)).Take(ctx)
+}
+
+// repository wallet
+type WalletRepository interface {
+	Repository[Wallet, string]
+
+	// mengambil wallet dengan lock (SELECT ... FOR UPDATE), harus dijalankan di dalam transaction
+	// contoh : db.Transaction(func(tx *gorm.DB) error { wallet, err := NewWalletRepository(tx).ForUpdate(ctx, id) ... })
+	ForUpdate(ctx context.Context, id string) (Wallet, error)
+}
+
+type GormWalletRepository struct {
+	*GormRepository[Wallet, string]
+}
+
+func NewWalletRepository(db *gorm.DB) *GormWalletRepository {
+	return &GormWalletRepository{GormRepository: NewRepository[Wallet, string](db)}
+}
+
+func (r *GormWalletRepository) ForUpdate(ctx context.Context, id string) (Wallet, error) {
+	return gorm.G[Wallet](r.db, clause.Locking{Strength: "UPDATE"}).Where(byID(id)).Take(ctx)
+}
@@ -0,0 +1,386 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi repository in-memory untuk unit test, tanpa database
+// perilaku nya mengikuti GormRepository : data yang tidak ditemukan mengembalikan gorm.ErrRecordNotFound,-
+// id yang sudah ada mengembalikan gorm.ErrDuplicatedKey, dan primary key integer yang kosong di isi otomatis
+// - hook BeforeCreate pada model dijalankan ketika Create, dengan *gorm.DB tanpa koneksi (hanya Context dan NowFunc)
+// - model dengan gorm.DeletedAt di soft delete, data yang sudah di hapus tidak ikut dibaca, di update ataupun dihitung
+// perbedaan dengan database : hook lain (BeforeUpdate, AfterCreate, dan lain lain), constraint (unique, foreign key)-
+// dan delete policy tidak dijalankan
+type MemoryRepository[T any, ID comparable] struct {
+	mu        sync.RWMutex
+	schema    *schema.Schema
+	rows      map[ID]T
+	lastID    int64
+	deletedAt *schema.Field // kolom soft delete, nil jika model tidak menggunakan soft delete
+	initErr   error
+
+	// sumber waktu untuk hook dan kolom autoCreateTime / autoUpdateTime, default time.Now
+	NowFunc func() time.Time
+}
+
+func NewMemoryRepository[T any, ID comparable]() *MemoryRepository[T, ID] {
+	s, err := schema.Parse(new(T), &sync.Map{}, schema.NamingStrategy{})
+	if err == nil && s.PrioritizedPrimaryField == nil {
+		err = fmt.Errorf("model %s has no primary key", s.Name)
+	}
+
+	repository := &MemoryRepository[T, ID]{schema: s, rows: map[ID]T{}, initErr: err, NowFunc: time.Now}
+	if err == nil {
+		for _, field := range s.Fields {
+			if field.FieldType == reflect.TypeOf(gorm.DeletedAt{}) {
+				repository.deletedAt = field
+			}
+		}
+	}
+
+	return repository
+}
+
+func (r *MemoryRepository[T, ID]) Get(ctx context.Context, id ID) (T, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	value, ok := r.find(ctx, id)
+	if !ok {
+		var zero T
+		return zero, gorm.ErrRecordNotFound
+	}
+
+	return value, nil
+}
+
+func (r *MemoryRepository[T, ID]) List(ctx context.Context, opts ListOptions) ([]T, error) {
+	if r.initErr != nil {
+		return nil, r.initErr
+	}
+
+	field := r.schema.PrioritizedPrimaryField
+	if opts.OrderBy != "" {
+		field = r.schema.LookUpField(opts.OrderBy)
+		if field == nil {
+			return nil, fmt.Errorf("unknown column %q", opts.OrderBy)
+		}
+	}
+
+	r.mu.RLock()
+	result := make([]T, 0, len(r.rows))
+	for _, value := range r.rows {
+		if r.alive(ctx, value) {
+			result = append(result, value)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		left := field.ReflectValueOf(ctx, reflect.ValueOf(&result[i]).Elem())
+		right := field.ReflectValueOf(ctx, reflect.ValueOf(&result[j]).Elem())
+		if opts.Desc {
+			return lessValue(right, left)
+		}
+		return lessValue(left, right)
+	})
+
+	if opts.Offset > 0 {
+		if opts.Offset >= len(result) {
+			return []T{}, nil
+		}
+		result = result[opts.Offset:]
+	}
+	if opts.Limit > 0 && opts.Limit < len(result) {
+		result = result[:opts.Limit]
+	}
+
+	return result, nil
+}
+
+func (r *MemoryRepository[T, ID]) Create(ctx context.Context, value *T) error {
+	if r.initErr != nil {
+		return r.initErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// hook BeforeCreate dijalankan sebelum primary key di isi, sama seperti gorm
+	if hook, ok := any(value).(callbacks.BeforeCreateInterface); ok {
+		err := hook.BeforeCreate(r.hookDB(ctx))
+		if err != nil {
+			return err
+		}
+	}
+
+	reflectValue := reflect.ValueOf(value).Elem()
+	primaryField := r.schema.PrioritizedPrimaryField
+
+	// primary key integer yang kosong di isi otomatis, seperti auto increment
+	if _, isZero := primaryField.ValueOf(ctx, reflectValue); isZero {
+		switch primaryField.FieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			r.lastID++
+			err := primaryField.Set(ctx, reflectValue, r.lastID)
+			if err != nil {
+				return err
+			}
+		default:
+			return errors.New("primary key is required")
+		}
+	}
+
+	id, err := r.idOf(ctx, reflectValue)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.rows[id]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+
+	// id yang di isi manual melanjutkan urutan auto increment
+	switch idValue := reflect.ValueOf(id); idValue.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		r.lastID = max(r.lastID, idValue.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		r.lastID = max(r.lastID, int64(idValue.Uint()))
+	}
+
+	r.touch(ctx, reflectValue, true)
+	r.rows[id] = *value
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Update(ctx context.Context, id ID, value T, fields ...string) error {
+	if r.initErr != nil {
+		return r.initErr
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.find(ctx, id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	source := reflect.ValueOf(&value).Elem()
+	target := reflect.ValueOf(&current).Elem()
+
+	var updateFields []*schema.Field
+	if len(fields) > 0 {
+		for _, name := range fields {
+			field := r.schema.LookUpField(name)
+			if field == nil {
+				return fmt.Errorf("unknown column %q", name)
+			}
+			updateFields = append(updateFields, field)
+		}
+	} else {
+		// tanpa field mask, hanya field yang tidak bernilai zero yang di update
+		for _, field := range r.schema.Fields {
+			if _, isZero := field.ValueOf(ctx, source); field.DBName != "" && !isZero {
+				updateFields = append(updateFields, field)
+			}
+		}
+	}
+
+	for _, field := range updateFields {
+		if field.PrimaryKey {
+			continue
+		}
+
+		fieldValue, _ := field.ValueOf(ctx, source)
+		err := field.Set(ctx, target, fieldValue)
+		if err != nil {
+			return err
+		}
+	}
+
+	r.touch(ctx, target, false)
+	r.rows[id] = current
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Delete(ctx context.Context, id ID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.find(ctx, id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+
+	if r.deletedAt == nil {
+		delete(r.rows, id)
+		return nil
+	}
+
+	// soft delete, data tetap disimpan (id nya tidak bisa dipakai ulang) dengan deleted_at terisi
+	err := r.deletedAt.Set(ctx, reflect.ValueOf(&current).Elem(), r.NowFunc())
+	if err != nil {
+		return err
+	}
+	r.rows[id] = current
+	return nil
+}
+
+func (r *MemoryRepository[T, ID]) Exists(ctx context.Context, id ID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.find(ctx, id)
+	return ok, nil
+}
+
+func (r *MemoryRepository[T, ID]) Count(ctx context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, value := range r.rows {
+		if r.alive(ctx, value) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// mengambil data yang belum di soft delete
+func (r *MemoryRepository[T, ID]) find(ctx context.Context, id ID) (T, bool) {
+	value, ok := r.rows[id]
+	if !ok || !r.alive(ctx, value) {
+		var zero T
+		return zero, false
+	}
+
+	return value, true
+}
+
+// true jika data belum di soft delete (atau model tidak menggunakan soft delete)
+func (r *MemoryRepository[T, ID]) alive(ctx context.Context, value T) bool {
+	if r.deletedAt == nil {
+		return true
+	}
+
+	_, isZero := r.deletedAt.ValueOf(ctx, reflect.ValueOf(&value).Elem())
+	return isZero
+}
+
+// *gorm.DB untuk hook, tanpa koneksi database
+func (r *MemoryRepository[T, ID]) hookDB(ctx context.Context) *gorm.DB {
+	return &gorm.DB{
+		Config:    &gorm.Config{NowFunc: r.NowFunc},
+		Statement: &gorm.Statement{Context: ctx},
+	}
+}
+
+func (r *MemoryRepository[T, ID]) idOf(ctx context.Context, reflectValue reflect.Value) (ID, error) {
+	var id ID
+
+	value, _ := r.schema.PrioritizedPrimaryField.ValueOf(ctx, reflectValue)
+	converted := reflect.ValueOf(value)
+	if !converted.CanConvert(reflect.TypeOf(id)) {
+		return id, fmt.Errorf("primary key %T can not be used as %T", value, id)
+	}
+
+	return converted.Convert(reflect.TypeOf(id)).Interface().(ID), nil
+}
+
+// mengisi kolom autoCreateTime dan autoUpdateTime bertipe time.Time
+func (r *MemoryRepository[T, ID]) touch(ctx context.Context, reflectValue reflect.Value, create bool) {
+	now := r.NowFunc()
+	for _, field := range r.schema.Fields {
+		if field.FieldType != reflect.TypeOf(time.Time{}) {
+			continue
+		}
+
+		_, isZero := field.ValueOf(ctx, reflectValue)
+		if field.AutoUpdateTime > 0 || (create && field.AutoCreateTime > 0 && isZero) {
+			field.Set(ctx, reflectValue, now)
+		}
+	}
+}
+
+// membandingkan value untuk pengurutan List
+func lessValue(left reflect.Value, right reflect.Value) bool {
+	for left.Kind() == reflect.Pointer {
+		if left.IsNil() {
+			return !right.IsNil()
+		}
+		left = left.Elem()
+	}
+	for right.Kind() == reflect.Pointer {
+		if right.IsNil() {
+			return false
+		}
+		right = right.Elem()
+	}
+
+	switch left.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return left.Int() < right.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return left.Uint() < right.Uint()
+	case reflect.Float32, reflect.Float64:
+		return left.Float() < right.Float()
+	case reflect.String:
+		return left.String() < right.String()
+	case reflect.Bool:
+		return !left.Bool() && right.Bool()
+	}
+
+	if leftTime, ok := left.Interface().(time.Time); ok {
+		return leftTime.Before(right.Interface().(time.Time))
+	}
+
+	return fmt.Sprint(left.Interface()) < fmt.Sprint(right.Interface())
+}
+
+// repository user in-memory
+type MemoryUserRepository struct {
+	*MemoryRepository[User, string]
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{MemoryRepository: NewMemoryRepository[User, string]()}
+}
+
+// relasi Wallet dan Addresses dikembalikan sesuai data yang di simpan ketika Create
+func (r *MemoryUserRepository) WithWalletAndAddresses(ctx context.Context, id string) (User, error) {
+	return r.Get(ctx, id)
+}
+
+// repository wallet in-memory
+type MemoryWalletRepository struct {
+	*MemoryRepository[Wallet, string]
+}
+
+func NewMemoryWalletRepository() *MemoryWalletRepository {
+	return &MemoryWalletRepository{MemoryRepository: NewMemoryRepository[Wallet, string]()}
+}
+
+// tidak ada lock yang dibutuhkan, akses data sudah di lindungi mutex
+func (r *MemoryWalletRepository) ForUpdate(ctx context.Context, id string) (Wallet, error) {
+	return r.Get(ctx, id)
+}
+
+var (
+	_ UserRepository   = (*GormUserRepository)(nil)
+	_ UserRepository   = (*MemoryUserRepository)(nil)
+	_ WalletRepository = (*GormWalletRepository)(nil)
+	_ WalletRepository = (*MemoryWalletRepository)(nil)
+)
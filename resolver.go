@@ -0,0 +1,214 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+// implementasi read/write splitting
+// query select (read) akan diarahkan ke database replica, sedangkan create, update, delete,-
+// raw exec dan semua query di dalam transaction tetap menggunakan database primary
+// resolver dipasang sebagai plugin, contoh : db.Use(NewResolver(RoundRobinPolicy(), replica1, replica2))
+type Resolver struct {
+	policy     ReplicaPolicy
+	dialectors []gorm.Dialector
+	replicas   []gorm.ConnPool
+}
+
+// policy untuk memilih replica mana yang digunakan (load balancing)
+type ReplicaPolicy interface {
+	Resolve(replicas []gorm.ConnPool) gorm.ConnPool
+}
+
+// memilih replica secara acak
+type randomPolicy struct{}
+
+func RandomPolicy() ReplicaPolicy {
+	return randomPolicy{}
+}
+
+func (randomPolicy) Resolve(replicas []gorm.ConnPool) gorm.ConnPool {
+	return replicas[rand.Intn(len(replicas))]
+}
+
+// memilih replica secara bergiliran (round robin)
+type roundRobinPolicy struct {
+	counter atomic.Uint64
+}
+
+func RoundRobinPolicy() ReplicaPolicy {
+	return &roundRobinPolicy{}
+}
+
+func (p *roundRobinPolicy) Resolve(replicas []gorm.ConnPool) gorm.ConnPool {
+	next := p.counter.Add(1) - 1
+	return replicas[next%uint64(len(replicas))]
+}
+
+// membuat resolver baru, replica di tentukan menggunakan dialector (mysql.Open(), sqlite.Open(), dsb)
+func NewResolver(policy ReplicaPolicy, replicas ...gorm.Dialector) *Resolver {
+	if policy == nil {
+		policy = RandomPolicy()
+	}
+
+	return &Resolver{
+		policy:     policy,
+		dialectors: replicas,
+	}
+}
+
+// nama plugin, wajib untuk implementasi interface gorm.Plugin
+func (r *Resolver) Name() string {
+	return "resolver"
+}
+
+// dipanggil ketika db.Use(resolver), membuka koneksi ke setiap replica dan mendaftarkan callback
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	for _, dialector := range r.dialectors {
+		replica, err := gorm.Open(dialector, &gorm.Config{Logger: db.Logger})
+		if err != nil {
+			return err
+		}
+
+		r.replicas = append(r.replicas, replica.ConnPool)
+	}
+
+	// query dan row adalah operasi read, diarahkan ke replica
+	err := db.Callback().Query().Before("gorm:query").Register("resolver:query", r.switchReplica)
+	if err != nil {
+		return err
+	}
+
+	err = db.Callback().Row().Before("gorm:row").Register("resolver:row", r.switchReplica)
+	if err != nil {
+		return err
+	}
+
+	// operasi write selalu ke primary, statement yang dipakai ulang (contoh tx.Find lalu tx.Delete)-
+	// masih menyimpan connection pool replica dari query sebelumnya
+	// dijalankan paling awal, sebelum gorm:begin_transaction membuka transaction pada connection pool statement
+	writes := []error{
+		db.Callback().Create().Before("*").Register("resolver:create_primary", r.switchPrimary),
+		db.Callback().Update().Before("*").Register("resolver:update_primary", r.switchPrimary),
+		db.Callback().Delete().Before("*").Register("resolver:delete_primary", r.switchPrimary),
+		db.Callback().Raw().Before("*").Register("resolver:raw_primary", r.switchPrimary),
+	}
+	for _, err := range writes {
+		if err != nil {
+			return err
+		}
+	}
+
+	// setelah operasi write, tandai request agar read berikutnya tetap ke primary (sticky)
+	err = db.Callback().Create().After("gorm:create").Register("resolver:create", markWritten)
+	if err != nil {
+		return err
+	}
+
+	err = db.Callback().Update().After("gorm:update").Register("resolver:update", markWritten)
+	if err != nil {
+		return err
+	}
+
+	err = db.Callback().Delete().After("gorm:delete").Register("resolver:delete", markWritten)
+	if err != nil {
+		return err
+	}
+
+	return db.Callback().Raw().After("gorm:raw").Register("resolver:raw", markWritten)
+}
+
+// mengganti connection pool statement menjadi replica jika memungkinkan
+func (r *Resolver) switchReplica(db *gorm.DB) {
+	if db.Error != nil || len(r.replicas) == 0 {
+		return
+	}
+
+	// query di dalam transaction wajib tetap di primary
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		return
+	}
+
+	// hint untuk memaksa query ke primary
+	if primary, ok := db.Get(primaryKey); ok && primary.(bool) {
+		return
+	}
+
+	ctx := db.Statement.Context
+	if forced, ok := ctx.Value(forcePrimaryKey{}).(bool); ok && forced {
+		return
+	}
+
+	// request yang sudah melakukan write, membaca dari primary agar tidak terkena replication lag
+	if sticky, ok := ctx.Value(stickyKey{}).(*stickySession); ok && sticky.written.Load() {
+		return
+	}
+
+	// raw sql selain select (misal insert ... returning) tetap ke primary
+	if sql := strings.TrimSpace(db.Statement.SQL.String()); sql != "" && !isReadQuery(sql) {
+		return
+	}
+
+	db.Statement.ConnPool = r.policy.Resolve(r.replicas)
+}
+
+// mengembalikan connection pool statement ke primary jika sebelumnya diarahkan ke replica
+// connection pool transaction tidak diubah
+func (r *Resolver) switchPrimary(db *gorm.DB) {
+	for _, replica := range r.replicas {
+		if db.Statement.ConnPool == replica {
+			db.Statement.ConnPool = db.ConnPool
+			return
+		}
+	}
+}
+
+func isReadQuery(sql string) bool {
+	keyword := strings.ToLower(strings.Fields(sql)[0])
+	return keyword == "select" || keyword == "with" || keyword == "show" || keyword == "explain"
+}
+
+func markWritten(db *gorm.DB) {
+	if db.Error != nil || db.Statement.Context == nil {
+		return
+	}
+
+	if sticky, ok := db.Statement.Context.Value(stickyKey{}).(*stickySession); ok {
+		sticky.written.Store(true)
+	}
+}
+
+// key untuk db.Set(), menandakan query dipaksa ke primary
+const primaryKey = "resolver:primary"
+
+type forcePrimaryKey struct{}
+
+type stickyKey struct{}
+
+type stickySession struct {
+	written atomic.Bool
+}
+
+// scopes untuk memaksa query menggunakan primary, contoh : db.Scopes(UsePrimary).Find(&users)
+func UsePrimary(db *gorm.DB) *gorm.DB {
+	return db.Set(primaryKey, true)
+}
+
+// memaksa semua query yang menggunakan context ini ke primary
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// menandai context sebagai satu request, setelah terjadi write di dalam request ini,-
+// maka read berikutnya akan diarahkan ke primary (sticky primary)
+func WithStickyPrimary(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(stickyKey{}).(*stickySession); ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, stickyKey{}, &stickySession{})
+}
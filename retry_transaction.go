@@ -0,0 +1,238 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// implementasi retry transaction
+// db.Transaction() akan langsung gagal ketika terjadi deadlock atau lock wait timeout,-
+// padahal error seperti ini biasanya akan berhasil jika transaction di ulang dari awal
+// RetryTransaction mengulang seluruh transaction dengan jeda (backoff + jitter) sampai batas tertentu
+type RetryOptions struct {
+	MaxAttempts  int           // jumlah maksimal percobaan, default 3
+	InitialDelay time.Duration // jeda awal, default 20ms, akan dikali 2 setiap percobaan
+	MaxDelay     time.Duration // jeda maksimal, default 1 detik
+	TxOptions    *sql.TxOptions
+	Metrics      *RetryMetrics // default menggunakan DefaultRetryMetrics
+}
+
+// alasan error yang boleh di retry
+const (
+	RetryReasonDeadlock      = "deadlock"
+	RetryReasonLockTimeout   = "lock_wait_timeout"
+	RetryReasonSerialization = "serialization_failure"
+	RetryReasonBusy          = "busy"
+)
+
+// menjalankan fn di dalam transaction, dan mengulang transaction jika terjadi error yang bisa di retry
+// error yang tidak bisa di retry akan dikembalikan apa adanya tanpa di ubah
+func RetryTransaction(ctx context.Context, db *gorm.DB, opts RetryOptions, fn func(tx *gorm.DB) error) error {
+	opts = opts.withDefaults()
+	delay := opts.InitialDelay
+
+	// di dalam transaction lain, db.Transaction() hanya membuat savepoint
+	// ketika deadlock, mysql sudah me-rollback seluruh transaction luar, sehingga mengulang savepoint akan berjalan di transaction yang sudah mati
+	// error dikembalikan tanpa retry, yang harus di ulang adalah transaction paling luar
+	if _, ok := db.Statement.ConnPool.(gorm.TxCommitter); ok {
+		opts.Metrics.attempt()
+		return db.WithContext(ctx).Transaction(fn, txOptions(opts.TxOptions)...)
+	}
+
+	for attempt := 1; ; attempt++ {
+		opts.Metrics.attempt()
+
+		err := db.WithContext(ctx).Transaction(fn, txOptions(opts.TxOptions)...)
+		if err == nil {
+			return nil
+		}
+
+		reason, retryable := RetryableReason(err)
+		if !retryable {
+			return err
+		}
+
+		if attempt >= opts.MaxAttempts {
+			opts.Metrics.exhausted(reason)
+			return fmt.Errorf("transaction failed after %d attempts: %w", attempt, err)
+		}
+
+		opts.Metrics.retry(reason)
+
+		// full jitter, jeda di acak antara 0 sampai delay agar transaction yang bentrok tidak mengulang bersamaan
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(rand.Int63n(int64(delay) + 1))):
+		}
+
+		delay *= 2
+		if delay > opts.MaxDelay {
+			delay = opts.MaxDelay
+		}
+	}
+}
+
+func txOptions(opts *sql.TxOptions) []*sql.TxOptions {
+	if opts == nil {
+		return nil
+	}
+
+	return []*sql.TxOptions{opts}
+}
+
+func (o RetryOptions) withDefaults() RetryOptions {
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 3
+	}
+	if o.InitialDelay <= 0 {
+		o.InitialDelay = 20 * time.Millisecond
+	}
+	if o.MaxDelay <= 0 {
+		o.MaxDelay = time.Second
+	}
+	if o.Metrics == nil {
+		o.Metrics = DefaultRetryMetrics
+	}
+
+	return o
+}
+
+// mengecek apakah error dari driver database boleh di retry
+// mysql : 1213 (deadlock) dan 1205 (lock wait timeout)
+// postgresql : 40001 (serialization failure) dan 40P01 (deadlock)
+// sqlite : SQLITE_BUSY (database is locked)
+func RetryableReason(err error) (string, bool) {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case 1213:
+			return RetryReasonDeadlock, true
+		case 1205:
+			return RetryReasonLockTimeout, true
+		}
+		return "", false
+	}
+
+	// driver postgresql (pgx maupun lib/pq) menyediakan method SQLState()
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) {
+		switch pgErr.SQLState() {
+		case "40001":
+			return RetryReasonSerialization, true
+		case "40P01":
+			return RetryReasonDeadlock, true
+		}
+		return "", false
+	}
+
+	// driver sqlite tidak memiliki tipe error yang seragam, sehingga di cek dari pesan error nya
+	message := err.Error()
+	if strings.Contains(message, "SQLITE_BUSY") || strings.Contains(message, "database is locked") {
+		return RetryReasonBusy, true
+	}
+
+	return "", false
+}
+
+// jumlah percobaan dan retry transaction, dikelompokkan berdasarkan alasan nya
+type RetryMetrics struct {
+	mu       sync.Mutex
+	attempts uint64
+	retries  map[string]uint64
+	exhausts map[string]uint64
+}
+
+// metrics yang digunakan jika RetryOptions.Metrics tidak di isi
+var DefaultRetryMetrics = NewRetryMetrics()
+
+func NewRetryMetrics() *RetryMetrics {
+	return &RetryMetrics{retries: map[string]uint64{}, exhausts: map[string]uint64{}}
+}
+
+func (m *RetryMetrics) attempt() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.attempts++
+}
+
+func (m *RetryMetrics) retry(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[reason]++
+}
+
+func (m *RetryMetrics) exhausted(reason string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.exhausts[reason]++
+}
+
+// jumlah seluruh percobaan transaction
+func (m *RetryMetrics) Attempts() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.attempts
+}
+
+// jumlah retry untuk alasan tertentu
+func (m *RetryMetrics) Retries(reason string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.retries[reason]
+}
+
+// jumlah transaction yang tetap gagal setelah mencapai batas percobaan
+func (m *RetryMetrics) Exhausted(reason string) uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.exhausts[reason]
+}
+
+// menulis metrics retry dalam format prometheus text
+func (m *RetryMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(w, "# HELP gorm_transaction_attempts_total Number of transaction attempts.\n"+
+		"# TYPE gorm_transaction_attempts_total counter\ngorm_transaction_attempts_total %d\n", m.attempts)
+	if err != nil {
+		return err
+	}
+
+	reasons := []string{RetryReasonDeadlock, RetryReasonLockTimeout, RetryReasonSerialization, RetryReasonBusy}
+
+	_, err = fmt.Fprint(w, "# HELP gorm_transaction_retries_total Number of transaction retries.\n# TYPE gorm_transaction_retries_total counter\n")
+	if err != nil {
+		return err
+	}
+	for _, reason := range reasons {
+		_, err = fmt.Fprintf(w, "gorm_transaction_retries_total{reason=%q} %d\n", reason, m.retries[reason])
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprint(w, "# HELP gorm_transaction_retries_exhausted_total Number of transactions that failed after the last retry.\n# TYPE gorm_transaction_retries_exhausted_total counter\n")
+	if err != nil {
+		return err
+	}
+	for _, reason := range reasons {
+		_, err = fmt.Fprintf(w, "gorm_transaction_retries_exhausted_total{reason=%q} %d\n", reason, m.exhausts[reason])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
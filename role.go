@@ -0,0 +1,40 @@
+package belajar_go_lang_gorm
+
+import "time"
+
+// implementasi role based access control (RBAC)
+// sebuah user bisa memiliki banyak role, dan sebuah role bisa memiliki banyak permission
+// relasi keduanya menggunakan many to many melalui tabel penghubung user_roles dan role_permissions
+type Role struct {
+	ID        int64     `gorm:"primary_key;column:id;autoIncrement"`
+	Name      string    `gorm:"column:name;uniqueIndex;size:100"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+
+	// many 2 many : menunjukkan tabel penghubung antara role dan permission
+	// joinForeignKey:role_id : menunjukkan foreign key pada tabel penghubung yang menghubungkan ke role
+	// joinReferences:permission_id : menunjukkan foreign key pada tabel penghubung yang menghubungkan ke permission
+	Permissions []Permission `gorm:"many2many:role_permissions;foreignKey:id;joinForeignKey:role_id;references:id;joinReferences:permission_id"`
+
+	// relasi balik ke user yang memiliki role ini
+	Users []User `gorm:"many2many:user_roles;foreignKey:id;joinForeignKey:role_id;references:id;joinReferences:user_id"`
+}
+
+// menentukan nama table
+func (r Role) TableName() string {
+	return "roles"
+}
+
+// permission ditulis dengan format 'resource:action' atau 'resource:action:scope'
+// contoh : 'todo:read' (hanya data milik sendiri) dan 'todo:read:any' (semua data)
+type Permission struct {
+	ID        int64     `gorm:"primary_key;column:id;autoIncrement"`
+	Name      string    `gorm:"column:name;uniqueIndex;size:100"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
+}
+
+// menentukan nama table
+func (p Permission) TableName() string {
+	return "permissions"
+}
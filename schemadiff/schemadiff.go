@@ -0,0 +1,285 @@
+// package schemadiff membandingkan schema model gorm dengan schema database yang sedang berjalan
+//
+// perbedaan yang dilaporkan :
+//   - tabel atau kolom yang belum ada di database (missing_table, missing_column)
+//   - kolom di database yang tidak ada di model (extra_column)
+//   - tipe data yang berbeda (type_mismatch), ukuran hanya dibandingkan jika model menggunakan tag size
+//   - nullability yang berbeda (nullability_mismatch)
+//   - index pada model yang belum ada di database (missing_index)
+package schemadiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type Kind string
+
+const (
+	MissingTable        Kind = "missing_table"
+	MissingColumn       Kind = "missing_column"
+	ExtraColumn         Kind = "extra_column"
+	TypeMismatch        Kind = "type_mismatch"
+	NullabilityMismatch Kind = "nullability_mismatch"
+	MissingIndex        Kind = "missing_index"
+)
+
+// satu perbedaan antara model dan database
+type Issue struct {
+	Kind     Kind   `json:"kind"`
+	Table    string `json:"table"`
+	Column   string `json:"column,omitempty"`
+	Index    string `json:"index,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (i Issue) String() string {
+	target := i.Table
+	switch {
+	case i.Column != "":
+		target += "." + i.Column
+	case i.Index != "":
+		target += " index " + i.Index
+	}
+
+	message := fmt.Sprintf("%s %s", i.Kind, target)
+	if i.Expected != "" || i.Actual != "" {
+		message += fmt.Sprintf(" (model: %s, database: %s)", i.Expected, i.Actual)
+	}
+	return message
+}
+
+type Report struct {
+	Tables []string `json:"tables"`
+	Issues []Issue  `json:"issues"`
+}
+
+// true jika ada perbedaan
+func (r Report) HasDrift() bool {
+	return len(r.Issues) > 0
+}
+
+func (r Report) WriteText(w io.Writer) error {
+	if !r.HasDrift() {
+		_, err := fmt.Fprintf(w, "no drift in %d tables\n", len(r.Tables))
+		return err
+	}
+
+	for _, issue := range r.Issues {
+		_, err := fmt.Fprintln(w, issue.String())
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d issues in %d tables\n", len(r.Issues), len(r.Tables))
+	return err
+}
+
+func (r Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// membandingkan semua model dengan database
+func Compare(db *gorm.DB, models ...interface{}) (Report, error) {
+	report := Report{Issues: []Issue{}}
+	cache := &sync.Map{}
+
+	for _, model := range models {
+		s, err := schema.Parse(model, cache, db.NamingStrategy)
+		if err != nil {
+			return report, err
+		}
+		report.Tables = append(report.Tables, s.Table)
+
+		issues, err := compareTable(db, model, s)
+		if err != nil {
+			return report, fmt.Errorf("schemadiff: table %s: %w", s.Table, err)
+		}
+		report.Issues = append(report.Issues, issues...)
+	}
+
+	return report, nil
+}
+
+func compareTable(db *gorm.DB, model interface{}, s *schema.Schema) ([]Issue, error) {
+	migrator := db.Migrator()
+	if !migrator.HasTable(model) {
+		return []Issue{{Kind: MissingTable, Table: s.Table}}, nil
+	}
+
+	columnTypes, err := migrator.ColumnTypes(model)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, columnType := range columnTypes {
+		live[strings.ToLower(columnType.Name())] = columnType
+	}
+
+	var issues []Issue
+	expected := map[string]bool{}
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+		expected[strings.ToLower(field.DBName)] = true
+
+		columnType, ok := live[strings.ToLower(field.DBName)]
+		if !ok {
+			issues = append(issues, Issue{Kind: MissingColumn, Table: s.Table, Column: field.DBName, Expected: db.Dialector.DataTypeOf(field)})
+			continue
+		}
+
+		issues = append(issues, compareColumn(db, s, field, columnType)...)
+	}
+
+	for _, columnType := range columnTypes {
+		if !expected[strings.ToLower(columnType.Name())] {
+			actual, _ := columnType.ColumnType()
+			issues = append(issues, Issue{Kind: ExtraColumn, Table: s.Table, Column: columnType.Name(), Actual: actual})
+		}
+	}
+
+	indexIssues, err := compareIndexes(db, model, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(issues, indexIssues...), nil
+}
+
+func compareColumn(db *gorm.DB, s *schema.Schema, field *schema.Field, columnType gorm.ColumnType) []Issue {
+	var issues []Issue
+
+	expectedType := strings.ToLower(db.Dialector.DataTypeOf(field))
+	actualType, ok := columnType.ColumnType()
+	if !ok {
+		actualType = columnType.DatabaseTypeName()
+	}
+	actualType = strings.ToLower(actualType)
+
+	mismatch := baseType(expectedType) != baseType(actualType)
+	if !mismatch && field.TagSettings["SIZE"] != "" {
+		if length, ok := columnType.Length(); ok && length != int64(field.Size) {
+			mismatch = true
+		}
+	}
+	if mismatch {
+		issues = append(issues, Issue{Kind: TypeMismatch, Table: s.Table, Column: field.DBName, Expected: expectedType, Actual: actualType})
+	}
+
+	// nullability hanya dilaporkan jika model secara jelas membutuhkan NOT NULL atau NULL
+	if nullable, ok := columnType.Nullable(); ok {
+		switch {
+		case field.NotNull && nullable:
+			issues = append(issues, Issue{Kind: NullabilityMismatch, Table: s.Table, Column: field.DBName, Expected: "NOT NULL", Actual: "NULL"})
+		case nullableField(field) && !nullable && !field.PrimaryKey:
+			issues = append(issues, Issue{Kind: NullabilityMismatch, Table: s.Table, Column: field.DBName, Expected: "NULL", Actual: "NOT NULL"})
+		}
+	}
+
+	return issues
+}
+
+func compareIndexes(db *gorm.DB, model interface{}, s *schema.Schema) ([]Issue, error) {
+	expected := s.ParseIndexes()
+	if len(expected) == 0 {
+		return nil, nil
+	}
+
+	indexes, err := db.Migrator().GetIndexes(model)
+	if err != nil {
+		return nil, err
+	}
+
+	live := map[string]bool{}
+	for _, index := range indexes {
+		live[strings.ToLower(index.Name())] = true
+		live[columnsKey(index.Columns())] = true
+	}
+
+	var issues []Issue
+	for _, index := range expected {
+		columns := make([]string, 0, len(index.Fields))
+		for _, option := range index.Fields {
+			if option.Field != nil {
+				columns = append(columns, option.DBName)
+			}
+		}
+
+		// index dianggap ada jika nama nya sama, atau ada index lain dengan kolom yang sama
+		if live[strings.ToLower(index.Name)] || live[columnsKey(columns)] {
+			continue
+		}
+
+		issues = append(issues, Issue{Kind: MissingIndex, Table: s.Table, Index: index.Name, Expected: strings.Join(columns, ",")})
+	}
+
+	return issues, nil
+}
+
+// tipe data tanpa ukuran dan atribut, dengan alias antar dialect di samakan
+// contoh "varchar(191)" => "varchar", "bigint unsigned" => "bigint", "character varying(100)" => "varchar"
+func baseType(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	if i := strings.Index(dataType, "("); i >= 0 {
+		dataType = dataType[:i]
+	}
+	dataType = strings.TrimSpace(dataType)
+
+	if name, ok := typeAliases[dataType]; ok {
+		return name
+	}
+	if i := strings.Index(dataType, " "); i >= 0 {
+		dataType = dataType[:i]
+	}
+	if name, ok := typeAliases[dataType]; ok {
+		return name
+	}
+
+	return dataType
+}
+
+var typeAliases = map[string]string{
+	"boolean":                  "tinyint",
+	"bool":                     "tinyint",
+	"integer":                  "int",
+	"int4":                     "int",
+	"int8":                     "bigint",
+	"double precision":         "double",
+	"character varying":        "varchar",
+	"timestamp with time zone": "timestamptz",
+}
+
+// field yang bisa berisi NULL : pointer, sql.Null* dan gorm.DeletedAt
+func nullableField(field *schema.Field) bool {
+	if field.FieldType.Kind() == reflect.Pointer {
+		return true
+	}
+
+	name := field.FieldType.Name()
+	return strings.HasPrefix(name, "Null") || name == "DeletedAt"
+}
+
+func columnsKey(columns []string) string {
+	sorted := make([]string, len(columns))
+	for i, column := range columns {
+		sorted[i] = strings.ToLower(column)
+	}
+	sort.Strings(sorted)
+
+	return "columns:" + strings.Join(sorted, ",")
+}
@@ -0,0 +1,149 @@
+package scopes
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// membuat scope dari value parameter request, contoh value "0,1000" untuk balance_between
+type Builder func(value string) (Scope, error)
+
+// registry scopes, digunakan handler http untuk menerapkan scopes berdasarkan nama parameter request
+// contoh : GET /wallets?balance_between=0,1000&order_by=balance:desc
+type Registry struct {
+	mu       sync.RWMutex
+	builders map[string]Builder
+}
+
+func NewRegistry() *Registry {
+	return &Registry{builders: map[string]Builder{}}
+}
+
+// registry dengan scopes bawaan :
+//
+//	balance_between=min,max     (min atau max boleh kosong)
+//	created_between=from,to     (format RFC3339, from atau to boleh kosong)
+//	name=q
+//	owned_by=user_id
+//	active=true
+//	order_by=field[:asc|desc]
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register("balance_between", buildBalanceBetween)
+	registry.Register("created_between", buildCreatedBetween)
+	registry.Register("name", func(value string) (Scope, error) {
+		return WithName(value), nil
+	})
+	registry.Register("owned_by", func(value string) (Scope, error) {
+		return OwnedBy(value), nil
+	})
+	registry.Register("active", func(value string) (Scope, error) {
+		active, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return func(db *gorm.DB) *gorm.DB { return db }, nil
+		}
+		return Active(), nil
+	})
+	registry.Register("order_by", func(value string) (Scope, error) {
+		field, dir, _ := strings.Cut(value, ":")
+		return OrderBy(field, dir), nil
+	})
+
+	return registry
+}
+
+// mendaftarkan scope baru, nama yang sama akan di timpa
+func (r *Registry) Register(name string, builder Builder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.builders[name] = builder
+}
+
+// membuat daftar scopes dari query parameter, parameter yang tidak terdaftar diabaikan (contoh page, limit)
+// scopes diurutkan berdasarkan nama agar query yang dihasilkan selalu sama
+func (r *Registry) FromValues(values url.Values) ([]Scope, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		if _, ok := r.builders[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var result []Scope
+	for _, name := range names {
+		for _, value := range values[name] {
+			scope, err := r.builders[name](value)
+			if err != nil {
+				return nil, fmt.Errorf("scope %s: %w", name, err)
+			}
+			result = append(result, scope)
+		}
+	}
+
+	return result, nil
+}
+
+// menerapkan scopes dari query parameter request ke db
+// contoh : query, err := registry.Apply(db.Model(&Wallet{}), r)
+func (r *Registry) Apply(db *gorm.DB, request *http.Request) (*gorm.DB, error) {
+	scopes, err := r.FromValues(request.URL.Query())
+	if err != nil {
+		return db, err
+	}
+
+	return db.Scopes(scopes...), nil
+}
+
+func buildBalanceBetween(value string) (Scope, error) {
+	minValue, maxValue, _ := strings.Cut(value, ",")
+
+	var bounds [2]interface{}
+	for i, bound := range []string{minValue, maxValue} {
+		if bound == "" {
+			continue
+		}
+
+		number, err := strconv.ParseInt(bound, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = number
+	}
+
+	return Between("balance", bounds[0], bounds[1]), nil
+}
+
+func buildCreatedBetween(value string) (Scope, error) {
+	fromValue, toValue, _ := strings.Cut(value, ",")
+
+	var bounds [2]time.Time
+	for i, bound := range []string{fromValue, toValue} {
+		if bound == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, bound)
+		if err != nil {
+			return nil, err
+		}
+		bounds[i] = parsed
+	}
+
+	return CreatedBetween(bounds[0], bounds[1]), nil
+}
@@ -0,0 +1,212 @@
+// package scopes berisi kumpulan scopes gorm dengan parameter yang bisa digabungkan
+//
+// setiap scope mengecek kolom yang digunakan terhadap schema model, sehingga scope yang-
+// diterapkan ke tabel yang tidak memiliki kolom tersebut akan menghasilkan error (ErrUnknownColumn)
+// dan bukan query sql yang gagal di database
+//
+// contoh :
+//
+//	db.Scopes(scopes.BalanceBetween(0, 1000), scopes.OrderBy("balance", "desc")).Find(&wallets)
+package scopes
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	ErrUnknownColumn = errors.New("unknown column")
+	ErrNoModel       = errors.New("scope requires a model")
+)
+
+// function scopes, sama seperti parameter db.Scopes()
+type Scope = func(db *gorm.DB) *gorm.DB
+
+// filter kolom balance, min dan max termasuk (inclusive)
+func BalanceBetween(min int64, max int64) Scope {
+	return Between("balance", min, max)
+}
+
+// filter kolom balance lebih besar atau sama dengan min
+func BalanceAtLeast(min int64) Scope {
+	return Between("balance", min, nil)
+}
+
+// filter kolom created_at, from termasuk (inclusive) dan to tidak termasuk (exclusive)
+// waktu yang kosong (zero) artinya tanpa batas
+func CreatedBetween(from time.Time, to time.Time) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		column, ok := lookupColumn(db, "created_at")
+		if !ok {
+			return db
+		}
+
+		if !from.IsZero() {
+			db = db.Where(clause.Gte{Column: column, Value: from})
+		}
+		if !to.IsZero() {
+			db = db.Where(clause.Lt{Column: column, Value: to})
+		}
+		return db
+	}
+}
+
+// filter kolom di antara min dan max (inclusive), nil artinya tanpa batas
+func Between(field string, min interface{}, max interface{}) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		column, ok := lookupColumn(db, field)
+		if !ok {
+			return db
+		}
+
+		if min != nil {
+			db = db.Where(clause.Gte{Column: column, Value: min})
+		}
+		if max != nil {
+			db = db.Where(clause.Lte{Column: column, Value: max})
+		}
+		return db
+	}
+}
+
+// pencarian nama (LIKE), menggunakan kolom name jika ada,-
+// atau first_name, middle_name dan last_name untuk model dengan embedded Name (contoh User)
+func WithName(q string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		if q == "" {
+			return db
+		}
+
+		s, ok := modelSchema(db)
+		if !ok {
+			return db
+		}
+
+		var columns []clause.Column
+		for _, name := range []string{"name", "first_name", "middle_name", "last_name"} {
+			if field := s.LookUpField(name); field != nil && field.DBName != "" {
+				columns = append(columns, clause.Column{Table: clause.CurrentTable, Name: field.DBName})
+			}
+		}
+		if len(columns) == 0 {
+			db.AddError(fmt.Errorf("%w: %s has no name column", ErrUnknownColumn, s.Table))
+			return db
+		}
+
+		pattern := "%" + escapeLike(q) + "%"
+		conditions := make([]clause.Expression, len(columns))
+		for i, column := range columns {
+			conditions[i] = likeEscaped(column, pattern)
+		}
+
+		return db.Where(clause.Or(conditions...))
+	}
+}
+
+// filter data milik user tertentu (kolom user_id)
+func OwnedBy(userID string) Scope {
+	return Equal("user_id", userID)
+}
+
+// filter kolom sama dengan value
+func Equal(field string, value interface{}) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		column, ok := lookupColumn(db, field)
+		if !ok {
+			return db
+		}
+
+		return db.Where(clause.Eq{Column: column, Value: value})
+	}
+}
+
+// hanya data yang belum di hapus (soft delete), model harus memiliki kolom deleted_at
+// berguna ketika query menggunakan Unscoped() tetapi tetap hanya ingin data yang aktif
+func Active() Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		column, ok := lookupColumn(db, "deleted_at")
+		if !ok {
+			return db
+		}
+
+		return db.Where(clause.Eq{Column: column, Value: nil})
+	}
+}
+
+// mengurutkan data berdasarkan kolom, dir bernilai asc atau desc
+// nama kolom di cek terhadap schema, sehingga aman digunakan dengan input dari request
+func OrderBy(field string, dir string) Scope {
+	return func(db *gorm.DB) *gorm.DB {
+		column, ok := lookupColumn(db, field)
+		if !ok {
+			return db
+		}
+
+		switch strings.ToLower(dir) {
+		case "", "asc":
+			return db.Order(clause.OrderByColumn{Column: column})
+		case "desc":
+			return db.Order(clause.OrderByColumn{Column: column, Desc: true})
+		default:
+			db.AddError(fmt.Errorf("invalid order direction %q", dir))
+			return db
+		}
+	}
+}
+
+// mengambil schema model dari statement (Model atau Dest)
+func modelSchema(db *gorm.DB) (*schema.Schema, bool) {
+	if db.Statement.Schema != nil {
+		return db.Statement.Schema, true
+	}
+
+	model := db.Statement.Model
+	if model == nil {
+		model = db.Statement.Dest
+	}
+	if model == nil {
+		db.AddError(ErrNoModel)
+		return nil, false
+	}
+
+	err := db.Statement.Parse(model)
+	if err != nil {
+		db.AddError(err)
+		return nil, false
+	}
+
+	return db.Statement.Schema, true
+}
+
+// mencari kolom berdasarkan nama kolom database atau nama field, contoh "balance" atau "Balance"
+func lookupColumn(db *gorm.DB, name string) (clause.Column, bool) {
+	s, ok := modelSchema(db)
+	if !ok {
+		return clause.Column{}, false
+	}
+
+	field := s.LookUpField(name)
+	if field == nil || field.DBName == "" {
+		db.AddError(fmt.Errorf("%w: %s.%s", ErrUnknownColumn, s.Table, name))
+		return clause.Column{}, false
+	}
+
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}, true
+}
+
+// escape karakter wildcard LIKE pada input pencarian
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// LIKE dengan ESCAPE '\' yang eksplisit, sqlite tidak memiliki escape character default
+// escape character dikirim sebagai parameter karena literal '\' ditulis berbeda di mysql ('\\') dan sqlite ('\')
+func likeEscaped(column clause.Column, pattern string) clause.Expression {
+	return clause.Expr{SQL: "? LIKE ? ESCAPE ?", Vars: []interface{}{column, pattern, `\`}}
+}
@@ -0,0 +1,338 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// implementasi structured logger menggunakan log/slog
+// berbeda dengan logger.Default yang menampilkan semua query beserta value nya (termasuk password),-
+// logger ini menampilkan log dalam bentuk json dan menyamarkan (redact) value dari kolom sensitif
+// kolom sensitif ditandai dengan tag 'sensitive' pada model, contoh : `gorm:"column:password;sensitive"`
+type SlogLogger struct {
+	logger        *slog.Logger
+	level         logger.LogLevel
+	slowThreshold time.Duration
+	sensitive     map[string]bool
+}
+
+// pengaturan slog logger
+type SlogLoggerConfig struct {
+	LogLevel      logger.LogLevel
+	SlowThreshold time.Duration // query yang lebih lama dari ini akan ditandai slow = true
+	Models        []interface{} // model yang kolom sensitif nya akan di redact
+}
+
+// value pengganti untuk kolom sensitif
+const redacted = "[REDACTED]"
+
+// membuat slog logger baru, kolom sensitif di ambil dari tag pada setiap model
+func NewSlogLogger(log *slog.Logger, config SlogLoggerConfig) (*SlogLogger, error) {
+	sensitive, err := SensitiveColumns(config.Models...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SlogLogger{
+		logger:        log,
+		level:         config.LogLevel,
+		slowThreshold: config.SlowThreshold,
+		sensitive:     sensitive,
+	}, nil
+}
+
+// mengambil nama kolom yang memiliki tag 'sensitive' dari model
+func SensitiveColumns(models ...interface{}) (map[string]bool, error) {
+	columns := map[string]bool{}
+
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, schema.NamingStrategy{})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, field := range s.Fields {
+			if _, ok := field.TagSettings["SENSITIVE"]; ok && field.DBName != "" {
+				columns[strings.ToLower(field.DBName)] = true
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+func (l *SlogLogger) LogMode(level logger.LogLevel) logger.Interface {
+	newLogger := *l
+	newLogger.level = level
+	return &newLogger
+}
+
+func (l *SlogLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Info {
+		l.logger.InfoContext(ctx, fmt.Sprintf(msg, data...), l.requestAttr(ctx)...)
+	}
+}
+
+func (l *SlogLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Warn {
+		l.logger.WarnContext(ctx, fmt.Sprintf(msg, data...), l.requestAttr(ctx)...)
+	}
+}
+
+func (l *SlogLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= logger.Error {
+		l.logger.ErrorContext(ctx, fmt.Sprintf(msg, data...), l.requestAttr(ctx)...)
+	}
+}
+
+// dipanggil oleh gorm setiap kali query selesai dijalankan
+func (l *SlogLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	slow := l.slowThreshold > 0 && elapsed > l.slowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+
+	var level slog.Level
+	switch {
+	case failed && l.level >= logger.Error:
+		level = slog.LevelError
+	case slow && l.level >= logger.Warn:
+		level = slog.LevelWarn
+	case l.level >= logger.Info:
+		level = slog.LevelInfo
+	default:
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []any{
+		slog.String("sql", sql),
+		slog.Float64("duration_ms", float64(elapsed.Nanoseconds())/1e6),
+		slog.Int64("rows", rows),
+		slog.String("caller", caller()),
+		slog.Bool("slow", slow),
+	}
+	attrs = append(attrs, l.requestAttr(ctx)...)
+
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+
+	l.logger.Log(ctx, level, "sql executed", attrs...)
+}
+
+// implementasi gorm.ParamsFilter, dipanggil sebelum value di render ke dalam sql
+// value yang di bind ke kolom sensitif akan diganti dengan [REDACTED]
+func (l *SlogLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if len(l.sensitive) == 0 || len(params) == 0 {
+		return sql, params
+	}
+
+	filtered := make([]interface{}, len(params))
+	copy(filtered, params)
+
+	for index, column := range placeholderColumns(sql, len(params)) {
+		if l.sensitive[column] {
+			filtered[index] = redacted
+		}
+	}
+
+	return sql, filtered
+}
+
+func (l *SlogLogger) requestAttr(ctx context.Context) []any {
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return []any{slog.String("request_id", requestID)}
+	}
+
+	return nil
+}
+
+type requestIDKey struct{}
+
+// menyimpan request id ke dalam context, agar ikut di tampilkan pada log query
+// contoh : db.WithContext(ContextWithRequestID(ctx, "req-1")).Find(&users)
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// mengambil request id dari context
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+// mencari nama kolom untuk setiap placeholder (? atau $n) pada sql
+// untuk insert, kolom di ambil dari daftar kolom sesuai posisi value nya,-
+// selain itu kolom di ambil dari identifier sebelum operator pembanding (contoh : `password` = ?)
+func placeholderColumns(sql string, count int) map[int]string {
+	columns := map[int]string{}
+	insertColumns := insertColumnList(sql)
+	valuesAt := -1
+	if insertColumns != nil {
+		valuesAt = strings.Index(strings.ToUpper(sql), "VALUES")
+	}
+
+	index, depth, position := 0, 0, 0
+	inString := false
+
+	for i := 0; i < len(sql) && index < count; i++ {
+		ch := sql[i]
+
+		if ch == '\'' {
+			inString = !inString
+			continue
+		}
+		if inString {
+			continue
+		}
+
+		// menghitung posisi value di dalam tuple insert (...), (...)
+		if valuesAt >= 0 && i > valuesAt {
+			switch ch {
+			case '(':
+				depth++
+				if depth == 1 {
+					position = 0
+				}
+			case ')':
+				depth--
+			case ',':
+				if depth == 1 {
+					position++
+				}
+			}
+		}
+
+		placeholder := -1
+		if ch == '?' {
+			placeholder = index
+			index++
+		} else if ch == '$' && i+1 < len(sql) && sql[i+1] >= '0' && sql[i+1] <= '9' {
+			j := i + 1
+			for j < len(sql) && sql[j] >= '0' && sql[j] <= '9' {
+				j++
+			}
+			n, _ := strconv.Atoi(sql[i+1 : j])
+			placeholder = n - 1
+			index++
+		}
+
+		if placeholder < 0 {
+			continue
+		}
+
+		if valuesAt >= 0 && i > valuesAt && depth == 1 && position < len(insertColumns) {
+			columns[placeholder] = insertColumns[position]
+		} else if column := columnBefore(sql[:i]); column != "" {
+			columns[placeholder] = column
+		} else if column := listColumn(sql[:i]); column != "" {
+			columns[placeholder] = column
+		}
+	}
+
+	return columns
+}
+
+// mengambil daftar kolom dari query insert into table (a, b, c) values ...
+func insertColumnList(sql string) []string {
+	upper := strings.ToUpper(strings.TrimSpace(sql))
+	if !strings.HasPrefix(upper, "INSERT") {
+		return nil
+	}
+
+	start := strings.Index(sql, "(")
+	end := strings.Index(sql, ")")
+	if start < 0 || end < start {
+		return nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(sql[start+1:end], ",") {
+		columns = append(columns, normalizeColumn(column))
+	}
+
+	return columns
+}
+
+// mengambil identifier terakhir sebelum operator pembanding
+func columnBefore(prefix string) string {
+	prefix = strings.TrimRight(prefix, " (")
+	upper := strings.ToUpper(prefix)
+
+	for _, operator := range []string{"<>", "!=", ">=", "<=", "=", "<", ">", " NOT LIKE", " LIKE", " NOT IN", " IN"} {
+		if strings.HasSuffix(upper, operator) {
+			prefix = strings.TrimSpace(prefix[:len(prefix)-len(operator)])
+			start := strings.LastIndexFunc(prefix, func(r rune) bool {
+				return !(r == '_' || r == '.' || r == '`' || r == '"' || unicode.IsLetter(r) || unicode.IsDigit(r))
+			})
+
+			return normalizeColumn(prefix[start+1:])
+		}
+	}
+
+	return ""
+}
+
+// mengambil kolom untuk placeholder kedua dan seterusnya di dalam daftar IN (?, ?, ?),-
+// placeholder sebelumnya di lewati sampai ditemukan kolom sebelum IN (
+func listColumn(prefix string) string {
+	trimmed := strings.TrimRight(prefix, " ,?$0123456789")
+	if trimmed == prefix || !strings.HasSuffix(trimmed, "(") {
+		return ""
+	}
+
+	upper := strings.ToUpper(strings.TrimRight(trimmed, " ("))
+	if !strings.HasSuffix(upper, " IN") {
+		return ""
+	}
+
+	return columnBefore(trimmed)
+}
+
+// menghapus quote dan nama tabel dari identifier, contoh `users`.`password` => password
+func normalizeColumn(identifier string) string {
+	identifier = strings.Trim(strings.TrimSpace(identifier), "`\"(,")
+	if dot := strings.LastIndex(identifier, "."); dot >= 0 {
+		identifier = identifier[dot+1:]
+	}
+
+	return strings.ToLower(strings.Trim(identifier, "`\""))
+}
+
+// mencari lokasi kode pemanggil query, melewati frame milik gorm dan logger ini sendiri
+func caller() string {
+	pcs := make([]uintptr, 20)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "gorm.io/") && !strings.Contains(frame.Function, ".(*SlogLogger).") {
+			return frame.File + ":" + strconv.Itoa(frame.Line)
+		}
+
+		if !more {
+			return ""
+		}
+	}
+}
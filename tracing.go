@@ -0,0 +1,329 @@
+package belajar_go_lang_gorm
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// implementasi plugin tracing dan metrics query
+// plugin ini mendaftarkan callback sebelum dan sesudah operasi create, query, update, delete, row dan raw
+// setiap query yang selesai akan dikirim ke exporter dalam bentuk span
+// contoh : db.Use(NewTracer(NewPrometheusExporter()))
+type Tracer struct {
+	exporters []SpanExporter
+}
+
+// span adalah catatan satu query yang sudah dijalankan
+type QuerySpan struct {
+	Operation   string
+	Table       string
+	Fingerprint string // sql yang sudah di normalisasi tanpa literal, sehingga query yang bentuk nya sama akan sama
+	Start       time.Time
+	Duration    time.Duration
+	Rows        int64
+	Err         error
+}
+
+// exporter menerima span dari tracer, bisa di implementasikan sesuai kebutuhan (log, prometheus, dsb)
+type SpanExporter interface {
+	ExportSpan(span QuerySpan)
+}
+
+// membuat tracer baru dengan satu atau lebih exporter
+func NewTracer(exporters ...SpanExporter) *Tracer {
+	return &Tracer{exporters: exporters}
+}
+
+func (t *Tracer) Name() string {
+	return "tracer"
+}
+
+const tracerStartKey = "tracer:start"
+
+func (t *Tracer) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registers := []error{
+		callbacks.Create().Before("gorm:create").Register("tracer:before_create", t.before),
+		callbacks.Create().After("gorm:create").Register("tracer:after_create", t.after("create")),
+		callbacks.Query().Before("gorm:query").Register("tracer:before_query", t.before),
+		callbacks.Query().After("gorm:query").Register("tracer:after_query", t.after("query")),
+		callbacks.Update().Before("gorm:update").Register("tracer:before_update", t.before),
+		callbacks.Update().After("gorm:update").Register("tracer:after_update", t.after("update")),
+		callbacks.Delete().Before("gorm:delete").Register("tracer:before_delete", t.before),
+		callbacks.Delete().After("gorm:delete").Register("tracer:after_delete", t.after("delete")),
+		callbacks.Row().Before("gorm:row").Register("tracer:before_row", t.before),
+		callbacks.Row().After("gorm:row").Register("tracer:after_row", t.after("row")),
+		callbacks.Raw().Before("gorm:raw").Register("tracer:before_raw", t.before),
+		callbacks.Raw().After("gorm:raw").Register("tracer:after_raw", t.after("raw")),
+	}
+
+	for _, err := range registers {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (t *Tracer) before(db *gorm.DB) {
+	db.InstanceSet(tracerStartKey, time.Now())
+}
+
+func (t *Tracer) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(tracerStartKey)
+		if !ok {
+			return
+		}
+		start := value.(time.Time)
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		span := QuerySpan{
+			Operation:   operation,
+			Table:       table,
+			Fingerprint: FingerprintSQL(db.Statement.SQL.String()),
+			Start:       start,
+			Duration:    time.Since(start),
+			Rows:        db.RowsAffected,
+			Err:         db.Error,
+		}
+
+		for _, exporter := range t.exporters {
+			exporter.ExportSpan(span)
+		}
+	}
+}
+
+var (
+	fingerprintString      = regexp.MustCompile(`'(?:[^']|'')*'`)
+	fingerprintNumber      = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	fingerprintPostgresArg = regexp.MustCompile(`\$\d+`)
+	fingerprintList        = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	fingerprintTuples      = regexp.MustCompile(`\(\?\)(?:\s*,\s*\(\?\))+`)
+	fingerprintSpace       = regexp.MustCompile(`\s+`)
+)
+
+// normalisasi sql menjadi fingerprint, semua literal string dan angka diganti dengan ?,-
+// daftar value (IN (?, ?, ?) atau VALUES (...), (...)) di ringkas menjadi satu
+func FingerprintSQL(sql string) string {
+	sql = fingerprintString.ReplaceAllString(sql, "?")
+	sql = fingerprintPostgresArg.ReplaceAllString(sql, "?")
+	sql = fingerprintNumber.ReplaceAllString(sql, "?")
+	sql = fingerprintList.ReplaceAllString(sql, "(?)")
+	sql = fingerprintTuples.ReplaceAllString(sql, "(?)")
+	sql = fingerprintSpace.ReplaceAllString(sql, " ")
+
+	return strings.TrimSpace(sql)
+}
+
+// batas bucket histogram durasi query (dalam detik)
+var DefaultQueryBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// histogram durasi query untuk satu kombinasi tabel dan operasi
+type QueryHistogram struct {
+	Table     string
+	Operation string
+	Buckets   []float64
+	Counts    []uint64 // jumlah query dengan durasi <= bucket (kumulatif)
+	Count     uint64
+	Sum       float64
+	Errors    uint64
+}
+
+type histogramKey struct {
+	table     string
+	operation string
+}
+
+// menyimpan histogram untuk setiap tabel dan operasi, digunakan oleh exporter
+type queryHistograms struct {
+	mu         sync.Mutex
+	buckets    []float64
+	histograms map[histogramKey]*QueryHistogram
+}
+
+func newQueryHistograms(buckets []float64) *queryHistograms {
+	if len(buckets) == 0 {
+		buckets = DefaultQueryBuckets
+	}
+
+	return &queryHistograms{buckets: buckets, histograms: map[histogramKey]*QueryHistogram{}}
+}
+
+func (h *queryHistograms) observe(span QuerySpan) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := histogramKey{table: span.Table, operation: span.Operation}
+	histogram, ok := h.histograms[key]
+	if !ok {
+		histogram = &QueryHistogram{
+			Table:     span.Table,
+			Operation: span.Operation,
+			Buckets:   h.buckets,
+			Counts:    make([]uint64, len(h.buckets)),
+		}
+		h.histograms[key] = histogram
+	}
+
+	seconds := span.Duration.Seconds()
+	for i, bucket := range h.buckets {
+		if seconds <= bucket {
+			histogram.Counts[i]++
+		}
+	}
+
+	histogram.Count++
+	histogram.Sum += seconds
+	if span.Err != nil && !errors.Is(span.Err, gorm.ErrRecordNotFound) {
+		histogram.Errors++
+	}
+}
+
+// mengambil salinan histogram, diurutkan berdasarkan tabel dan operasi
+func (h *queryHistograms) snapshot() []QueryHistogram {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make([]QueryHistogram, 0, len(h.histograms))
+	for _, histogram := range h.histograms {
+		copied := *histogram
+		copied.Counts = append([]uint64(nil), histogram.Counts...)
+		result = append(result, copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Table != result[j].Table {
+			return result[i].Table < result[j].Table
+		}
+		return result[i].Operation < result[j].Operation
+	})
+
+	return result
+}
+
+// exporter yang menyimpan span dan histogram di memory, cocok untuk pengujian
+type InMemoryExporter struct {
+	mu         sync.Mutex
+	spans      []QuerySpan
+	histograms *queryHistograms
+}
+
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{histograms: newQueryHistograms(nil)}
+}
+
+func (e *InMemoryExporter) ExportSpan(span QuerySpan) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, span)
+	e.histograms.observe(span)
+}
+
+// mengambil semua span yang sudah di catat
+func (e *InMemoryExporter) Spans() []QuerySpan {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]QuerySpan(nil), e.spans...)
+}
+
+// mengambil histogram untuk tabel dan operasi tertentu
+func (e *InMemoryExporter) Histogram(table string, operation string) (QueryHistogram, bool) {
+	e.mu.Lock()
+	histograms := e.histograms
+	e.mu.Unlock()
+
+	for _, histogram := range histograms.snapshot() {
+		if histogram.Table == table && histogram.Operation == operation {
+			return histogram, true
+		}
+	}
+
+	return QueryHistogram{}, false
+}
+
+// menghapus semua span dan histogram
+func (e *InMemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = nil
+	e.histograms = newQueryHistograms(nil)
+}
+
+// exporter yang menampilkan histogram dalam format prometheus text
+type PrometheusExporter struct {
+	histograms *queryHistograms
+}
+
+// membuat prometheus exporter, jika buckets kosong maka menggunakan DefaultQueryBuckets
+func NewPrometheusExporter(buckets ...float64) *PrometheusExporter {
+	return &PrometheusExporter{histograms: newQueryHistograms(buckets)}
+}
+
+func (e *PrometheusExporter) ExportSpan(span QuerySpan) {
+	e.histograms.observe(span)
+}
+
+// menulis metrics dalam format prometheus text
+func (e *PrometheusExporter) WritePrometheus(w io.Writer) error {
+	histograms := e.histograms.snapshot()
+
+	_, err := fmt.Fprint(w, "# HELP gorm_query_duration_seconds Duration of GORM queries.\n# TYPE gorm_query_duration_seconds histogram\n")
+	if err != nil {
+		return err
+	}
+
+	for _, h := range histograms {
+		labels := fmt.Sprintf("table=%q,operation=%q", h.Table, h.Operation)
+		for i, bucket := range h.Buckets {
+			_, err = fmt.Fprintf(w, "gorm_query_duration_seconds_bucket{%s,le=\"%g\"} %d\n", labels, bucket, h.Counts[i])
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = fmt.Fprintf(w, "gorm_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n"+
+			"gorm_query_duration_seconds_sum{%s} %g\n"+
+			"gorm_query_duration_seconds_count{%s} %d\n", labels, h.Count, labels, h.Sum, labels, h.Count)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprint(w, "# HELP gorm_query_errors_total Number of GORM queries that returned an error.\n# TYPE gorm_query_errors_total counter\n")
+	if err != nil {
+		return err
+	}
+
+	for _, h := range histograms {
+		_, err = fmt.Fprintf(w, "gorm_query_errors_total{table=%q,operation=%q} %d\n", h.Table, h.Operation, h.Errors)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// http handler untuk endpoint /metrics
+func (e *PrometheusExporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	e.WritePrometheus(w)
+}
@@ -0,0 +1,145 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// implementasi unit of work dengan nested transaction (savepoint)
+// contoh :
+//
+//	err := RunUnitOfWork(ctx, db, func(uow *UnitOfWork) error {
+//		uow.Step("create user", func(tx *gorm.DB) error { return tx.Create(&user).Error })
+//		uow.Nested(func(inner *UnitOfWork) error { ... }) // jika gagal, hanya bagian ini yang di rollback
+//		uow.AfterCommit(func() { cache.Invalidate(user.ID) })
+//		return nil
+//	})
+type UnitOfWork struct {
+	tx          *gorm.DB
+	afterCommit []func()
+	failures    []StepError
+}
+
+// step yang gagal beserta error nya
+type StepError struct {
+	Step string
+	Err  error
+}
+
+func (e StepError) Error() string {
+	return e.Step + ": " + e.Err.Error()
+}
+
+func (e StepError) Unwrap() error {
+	return e.Err
+}
+
+// error dari unit of work, berisi semua step yang gagal (bukan hanya yang pertama)
+type UnitOfWorkError struct {
+	Steps []StepError
+	Err   error // error yang dikembalikan oleh function unit of work, jika ada
+}
+
+func (e *UnitOfWorkError) Error() string {
+	messages := make([]string, 0, len(e.Steps)+1)
+	if e.Err != nil {
+		messages = append(messages, e.Err.Error())
+	}
+	for _, step := range e.Steps {
+		messages = append(messages, step.Error())
+	}
+
+	return "unit of work failed: " + strings.Join(messages, "; ")
+}
+
+// agar errors.Is dan errors.As bisa mengecek error setiap step
+func (e *UnitOfWorkError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Steps)+1)
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	for _, step := range e.Steps {
+		errs = append(errs, step)
+	}
+
+	return errs
+}
+
+// menjalankan unit of work di dalam transaction
+// transaction di rollback jika fn mengembalikan error, terjadi panic, atau ada step yang gagal
+// callback AfterCommit hanya dijalankan setelah commit berhasil
+func RunUnitOfWork(ctx context.Context, db *gorm.DB, fn func(uow *UnitOfWork) error) error {
+	uow := &UnitOfWork{}
+
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		uow.tx = tx
+		return uow.run(fn)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, callback := range uow.afterCommit {
+		callback()
+	}
+
+	return nil
+}
+
+func (u *UnitOfWork) run(fn func(uow *UnitOfWork) error) error {
+	err := fn(u)
+	if err == nil && len(u.failures) == 0 {
+		return nil
+	}
+
+	var uowErr *UnitOfWorkError
+	if len(u.failures) == 0 && errors.As(err, &uowErr) {
+		return err
+	}
+
+	return &UnitOfWorkError{Steps: u.failures, Err: err}
+}
+
+// koneksi transaction untuk menjalankan query
+func (u *UnitOfWork) DB() *gorm.DB {
+	return u.tx
+}
+
+// menjalankan fn di dalam savepoint, jika gagal hanya perubahan di dalam fn yang di rollback
+// error dikembalikan ke pemanggil, sehingga pemanggil bisa memilih untuk melanjutkan atau membatalkan
+// callback AfterCommit yang didaftarkan di dalam fn diabaikan jika fn gagal
+func (u *UnitOfWork) Nested(fn func(uow *UnitOfWork) error) error {
+	nested := &UnitOfWork{}
+
+	// tx.Transaction di dalam transaction otomatis menggunakan SAVEPOINT dan ROLLBACK TO SAVEPOINT
+	err := u.tx.Transaction(func(tx *gorm.DB) error {
+		nested.tx = tx
+		return nested.run(fn)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.afterCommit = append(u.afterCommit, nested.afterCommit...)
+	return nil
+}
+
+// menjalankan satu step di dalam savepoint, step yang gagal di rollback dan dicatat,-
+// step berikutnya tetap dijalankan, dan unit of work akan gagal dengan daftar semua step yang gagal
+func (u *UnitOfWork) Step(name string, fn func(tx *gorm.DB) error) error {
+	err := u.tx.Transaction(fn)
+	if err != nil {
+		u.failures = append(u.failures, StepError{Step: name, Err: err})
+	}
+
+	return err
+}
+
+// mendaftarkan callback yang dijalankan setelah transaction berhasil di commit,-
+// contoh untuk invalidasi cache atau mengirim event
+func (u *UnitOfWork) AfterCommit(callback func()) {
+	u.afterCommit = append(u.afterCommit, callback)
+}
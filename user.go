@@ -1,6 +1,7 @@
 package belajar_go_lang_gorm
 
 import (
+	"errors"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,7 +11,7 @@ import (
 // sehingga contoh kalau nama tabel / struct User => 'users' dan atau OrderDetail => 'order_details'
 type User struct {
 	ID        string `gorm:"primary_key;column:id;<-:create"` // kolom id datanya hanya boleh dicreate saja, tidak boleh di update
-	Password  string `gorm:"column:password"`
+	Password  string `gorm:"column:password;sensitive"` // sensitive : value nya akan di redact pada log query (SlogLogger)
 
 	// field name sebagai embedded struct Name
 	Name Name `gorm:"embedded"` // sebagai embedded, maka secara otomatis kolom di struct Name akan ditambahkan secara embedded disini
@@ -25,10 +26,16 @@ type User struct {
 	Information string `gorm:"-"` // di abaikan / tidak ada kolom nya di database
 
 	// implementasi one to one (has one)
-	Wallet Wallet `gorm:"foreignKey:user_id;references:id"`
+	// deletePolicy : aturan ketika user di hapus (lihat delete_policy.go)
+	// restrict : user yang masih memiliki wallet tidak boleh di hapus
+	Wallet Wallet `gorm:"foreignKey:user_id;references:id;deletePolicy:restrict"`
 
 	// implementasi one to many (has many)
-	Addresses []Address `gorm:"foreignKey:user_id;references:id"`
+	// cascade : address ikut di hapus ketika user di hapus
+	Addresses []Address `gorm:"foreignKey:user_id;references:id;deletePolicy:cascade"`
+
+	// soft_cascade : todo ikut di soft delete ketika user di hapus
+	Todos []Todo `gorm:"foreignKey:user_id;references:id;deletePolicy:soft_cascade"`
 
 	// implementasi relasi many to many
 	// menambahkan relasi ke tabel penghubung menuju ke tabel product sebagai many to many
@@ -38,7 +45,11 @@ type User struct {
 	// joinForeignKey:user_id : menunjukkan foreign key pada tabel penghubung yang menghubungkan ke user
 	// references:id : menunjukkan id (field primary key di tabel lain (product)
 	// joinReferences:product_id : menunjukkan foreign key pada tabel penghubung yang menghubungkan ke product
-	LikeProducts []Product `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:user_id;references:id;joinReferences:product_id"`
+	LikeProducts []Product `gorm:"many2many:user_like_product;foreignKey:id;joinForeignKey:user_id;references:id;joinReferences:product_id;deletePolicy:cascade"`
+
+	// implementasi role based access control
+	// role yang dimiliki user, melalui tabel penghubung user_roles
+	Roles []Role `gorm:"many2many:user_roles;foreignKey:id;joinForeignKey:user_id;references:id;joinReferences:role_id;deletePolicy:cascade"`
 }
 
 // membuat method baru untuk mengganti nama tabel (alias)
@@ -46,6 +57,19 @@ func (u *User) TableName() string {
 	return "users"
 }
 
+// implementasi validasi, digunakan oleh importer sebelum data di simpan
+func (u *User) Validate() error {
+	if u.Password == "" {
+		return errors.New("password is required")
+	}
+
+	if u.Name.FirstName == "" {
+		return errors.New("first_name is required")
+	}
+
+	return nil
+}
+
 // implementasi embedded struct
 // membuat struct baru untuk embedded struct
 type Name struct {
@@ -63,7 +87,8 @@ func (u *User) BeforeCreate(db *gorm.DB) error {
 	if u.ID == "" {
 		// contoh mengubah id nya menjadi kustom
 		// mengatur format waktu nya (tahun-bulan-tanggal-jam-menit-detik)
-		u.ID = "user-" + time.Now().Format("20060102150405") 
+		// waktu diambil dari db.NowFunc() agar mengikuti Clock yang digunakan (lihat clock.go)
+		u.ID = "user-" + db.NowFunc().Format("20060102150405")
 	}
 	
 	return nil
@@ -0,0 +1,313 @@
+package belajar_go_lang_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// implementasi jenis action pada user log (enum)
+// action harus di daftarkan terlebih dahulu beserta tipe payload nya menggunakan RegisterUserAction()
+type UserAction string
+
+const (
+	ActionLogin         UserAction = "login"
+	ActionLogout        UserAction = "logout"
+	ActionWalletDebit   UserAction = "wallet.debit"
+	ActionWalletCredit  UserAction = "wallet.credit"
+	ActionProfileUpdate UserAction = "profile.update"
+
+	// action untuk data lama yang action nya masih berupa teks bebas, teks aslinya disimpan pada LegacyPayload
+	ActionLegacy UserAction = "legacy"
+)
+
+// payload untuk action login
+type LoginPayload struct {
+	IP        string `json:"ip"`
+	UserAgent string `json:"user_agent"`
+}
+
+// payload untuk action wallet.debit dan wallet.credit
+type WalletPayload struct {
+	WalletID string `json:"wallet_id"`
+	Amount   int64  `json:"amount"`
+	Balance  int64  `json:"balance"`
+}
+
+// payload untuk action profile.update
+type ProfileUpdatePayload struct {
+	Fields []string `json:"fields"`
+}
+
+// payload untuk action legacy, berisi action lama sebelum menggunakan enum
+type LegacyPayload struct {
+	Action string `json:"action"`
+}
+
+var (
+	userActionsMu sync.RWMutex
+	userActions   = map[UserAction]reflect.Type{}
+)
+
+func init() {
+	RegisterUserAction(ActionLogin, LoginPayload{})
+	RegisterUserAction(ActionLogout, nil)
+	RegisterUserAction(ActionWalletDebit, WalletPayload{})
+	RegisterUserAction(ActionWalletCredit, WalletPayload{})
+	RegisterUserAction(ActionProfileUpdate, ProfileUpdatePayload{})
+	RegisterUserAction(ActionLegacy, LegacyPayload{})
+}
+
+// mendaftarkan action baru beserta contoh tipe payload nya, payload nil artinya action tanpa payload
+func RegisterUserAction(action UserAction, payload interface{}) {
+	userActionsMu.Lock()
+	defer userActionsMu.Unlock()
+
+	var payloadType reflect.Type
+	if payload != nil {
+		payloadType = reflect.Indirect(reflect.ValueOf(payload)).Type()
+	}
+
+	userActions[action] = payloadType
+}
+
+func userActionPayloadType(action UserAction) (reflect.Type, bool) {
+	userActionsMu.RLock()
+	defer userActionsMu.RUnlock()
+
+	payloadType, ok := userActions[action]
+	return payloadType, ok
+}
+
+// mengecek apakah action sudah terdaftar
+func (a UserAction) Validate() error {
+	if _, ok := userActionPayloadType(a); !ok {
+		return fmt.Errorf("unknown user action %q", a)
+	}
+
+	return nil
+}
+
+// membuat user log baru, tipe payload harus sesuai dengan yang di daftarkan untuk action tersebut
+func NewUserLog(userID string, action UserAction, payload interface{}) (UserLog, error) {
+	payloadType, ok := userActionPayloadType(action)
+	if !ok {
+		return UserLog{}, fmt.Errorf("unknown user action %q", action)
+	}
+
+	log := UserLog{UserId: userID, Action: action}
+
+	if payloadType == nil {
+		if payload != nil {
+			return UserLog{}, fmt.Errorf("user action %q does not accept a payload", action)
+		}
+		return log, nil
+	}
+
+	if payload == nil || reflect.Indirect(reflect.ValueOf(payload)).Type() != payloadType {
+		return UserLog{}, fmt.Errorf("user action %q expects payload %s, got %T", action, payloadType, payload)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return UserLog{}, err
+	}
+	log.Payload = data
+
+	return log, nil
+}
+
+// mengubah action lama yang belum terdaftar menjadi ActionLegacy, action aslinya disimpan di payload
+// action yang sudah terdaftar tidak diubah
+func legacyUserLog(log *UserLog) error {
+	if log.Action.Validate() == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(LegacyPayload{Action: string(log.Action)})
+	if err != nil {
+		return err
+	}
+
+	log.Action = ActionLegacy
+	log.Payload = data
+	return nil
+}
+
+// membaca payload sesuai tipe yang di daftarkan untuk action nya, contoh hasil nya LoginPayload
+func (u *UserLog) DecodePayload() (interface{}, error) {
+	payloadType, ok := userActionPayloadType(u.Action)
+	if !ok {
+		return nil, fmt.Errorf("unknown user action %q", u.Action)
+	}
+
+	if payloadType == nil || len(u.Payload) == 0 {
+		return nil, nil
+	}
+
+	payload := reflect.New(payloadType)
+	err := json.Unmarshal(u.Payload, payload.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	return payload.Elem().Interface(), nil
+}
+
+// filter untuk timeline aktivitas user
+type ActivityFilter struct {
+	Actions []UserAction // jika kosong, semua action
+	From    time.Time    // jika kosong, tanpa batas awal
+	To      time.Time    // jika kosong, tanpa batas akhir
+	Limit   int
+}
+
+// implementasi scopes, filter user log berdasarkan jenis action
+func ActionIn(actions ...UserAction) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(actions) == 0 {
+			return db
+		}
+		return db.Where("action IN ?", actions)
+	}
+}
+
+// implementasi scopes, filter user log berdasarkan rentang waktu (kolom created_at dalam milli)
+func LoggedBetween(from time.Time, to time.Time) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			db = db.Where("created_at >= ?", from.UnixMilli())
+		}
+		if !to.IsZero() {
+			db = db.Where("created_at < ?", to.UnixMilli())
+		}
+		return db
+	}
+}
+
+// mengambil timeline aktivitas user, diurutkan dari yang terbaru
+func UserActivityTimeline(ctx context.Context, db *gorm.DB, userID string, filter ActivityFilter) ([]UserLog, error) {
+	query := db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Scopes(ActionIn(filter.Actions...), LoggedBetween(filter.From, filter.To)).
+		Order("created_at DESC").Order("id DESC")
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var logs []UserLog
+	err := query.Find(&logs).Error
+	return logs, err
+}
+
+// migrasi tabel user_logs lama
+// - kolom id yang masih bertipe string diubah menjadi integer auto increment,-
+// id yang bukan angka akan diberikan id baru terlebih dahulu (melanjutkan id terbesar)
+// - menambahkan kolom payload jika belum ada
+// - action teks bebas yang belum terdaftar diubah menjadi ActionLegacy (action asli disimpan di payload)
+func MigrateUserLogs(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	migrator := db.Migrator()
+
+	columnTypes, err := migrator.ColumnTypes(&UserLog{})
+	if err != nil {
+		return err
+	}
+
+	for _, columnType := range columnTypes {
+		if columnType.Name() != "id" || isIntegerColumn(columnType.DatabaseTypeName()) {
+			continue
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			var ids []string
+			err := tx.Table("user_logs").Pluck("id", &ids).Error
+			if err != nil {
+				return err
+			}
+
+			var maxID int64
+			var invalid []string
+			for _, id := range ids {
+				number, err := strconv.ParseInt(id, 10, 64)
+				if err != nil || number <= 0 {
+					invalid = append(invalid, id)
+					continue
+				}
+				if number > maxID {
+					maxID = number
+				}
+			}
+
+			for _, id := range invalid {
+				maxID++
+				err = tx.Table("user_logs").Where("id = ?", id).Update("id", strconv.FormatInt(maxID, 10)).Error
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = migrator.AlterColumn(&UserLog{}, "ID")
+		if err != nil {
+			return err
+		}
+	}
+
+	if !migrator.HasColumn(&UserLog{}, "Payload") {
+		err = migrator.AddColumn(&UserLog{}, "Payload")
+		if err != nil {
+			return err
+		}
+	}
+
+	return migrateLegacyActions(db)
+}
+
+// action lama yang belum terdaftar diubah menjadi ActionLegacy, agar data nya lolos validasi hook-
+// ketika di update maupun ketika di restore dari archive
+func migrateLegacyActions(db *gorm.DB) error {
+	var actions []string
+	err := db.Table("user_logs").Where("action IS NOT NULL").Distinct("action").Pluck("action", &actions).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, action := range actions {
+			log := UserLog{Action: UserAction(action)}
+			err := legacyUserLog(&log)
+			if err != nil {
+				return err
+			}
+			if log.Action == UserAction(action) {
+				continue
+			}
+
+			err = tx.Table("user_logs").Where("action = ?", action).
+				Updates(map[string]interface{}{"action": log.Action, "payload": log.Payload}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func isIntegerColumn(databaseType string) bool {
+	return strings.Contains(strings.ToUpper(databaseType), "INT")
+}
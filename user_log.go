@@ -1,10 +1,22 @@
 package belajar_go_lang_gorm
 
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
 // implementasi auto increment, menambahkan tabel dan model baru
 type UserLog struct {
-	ID        string `gorm:"primary_key;column:id;autoIncrement"` 
-	UserId  string `gorm:"column:user_id"`
-	Action  string `gorm:"column:action"`
+	// id menggunakan tipe integer agar auto increment di kelola oleh database
+	// untuk tabel lama yang kolom id nya masih string, jalankan MigrateUserLogs()
+	ID     int64      `gorm:"primary_key;column:id;autoIncrement"`
+	UserId string     `gorm:"column:user_id"`
+	Action UserAction `gorm:"column:action;size:100"`
+
+	// data tambahan sesuai jenis action dalam bentuk json, contoh LoginPayload untuk action login
+	// gunakan NewUserLog() untuk membuat user log beserta payload nya, dan DecodePayload() untuk membaca nya
+	Payload json.RawMessage `gorm:"column:payload;type:json"`
 
 	// implementasi timestamp tracking
 	// mengubah tipe data timestamp dari time.Time menjadi int64(big int)
@@ -19,3 +31,17 @@ type UserLog struct {
 func (u *UserLog) TableName() string {
 	return "user_logs"
 }
+
+// implementasi hook, memastikan action yang disimpan sudah terdaftar
+func (u *UserLog) BeforeCreate(tx *gorm.DB) error {
+	return u.Action.Validate()
+}
+
+// ketika update, action hanya di cek jika ikut di ubah (tidak kosong)
+func (u *UserLog) BeforeUpdate(tx *gorm.DB) error {
+	if u.Action == "" {
+		return nil
+	}
+
+	return u.Action.Validate()
+}
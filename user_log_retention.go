@@ -0,0 +1,378 @@
+package belajar_go_lang_gorm
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// implementasi retention policy untuk tabel user_logs
+// data user_logs yang lebih lama dari N hari (berdasarkan kolom created_at dalam milli) dipindahkan ke-
+// file archive ndjson yang di compress (gzip) di disk lokal, kemudian dihapus dari database per batch
+type RetentionPolicy struct {
+	Days       int    // data yang lebih lama dari sekian hari akan di archive
+	ArchiveDir string // folder tempat menyimpan file archive
+	BatchSize  int    // jumlah data per batch (per file archive), default 1000
+
+	// mode partisi (khusus mysql), tabel user_logs di partisi per bulan berdasarkan created_at,-
+	// partisi yang sudah kosong setelah di archive akan di drop, dan partisi bulan berikutnya dibuat otomatis
+	Partitioned bool
+	MonthsAhead int // jumlah partisi bulan ke depan yang disiapkan, default 3
+
+	Now func() time.Time // default db.NowFunc (mengikuti Clock), bisa di ganti untuk pengujian
+}
+
+// hasil retention
+type RetentionResult struct {
+	Archived          int
+	Files             []string
+	DroppedPartitions []string
+}
+
+type UserLogRetention struct {
+	db     *gorm.DB
+	policy RetentionPolicy
+}
+
+func NewUserLogRetention(db *gorm.DB, policy RetentionPolicy) *UserLogRetention {
+	if policy.BatchSize <= 0 {
+		policy.BatchSize = 1000
+	}
+	if policy.MonthsAhead <= 0 {
+		policy.MonthsAhead = 3
+	}
+	if policy.Now == nil {
+		policy.Now = db.NowFunc
+	}
+
+	return &UserLogRetention{db: db, policy: policy}
+}
+
+// batas waktu retention dalam milli, data dengan created_at lebih kecil dari ini akan di archive
+func (r *UserLogRetention) Cutoff() int64 {
+	return r.policy.Now().AddDate(0, 0, -r.policy.Days).UnixMilli()
+}
+
+// menjalankan retention : archive dan hapus data lama, kemudian mengatur partisi jika mode partisi aktif
+func (r *UserLogRetention) Run(ctx context.Context) (RetentionResult, error) {
+	var result RetentionResult
+	cutoff := r.Cutoff()
+
+	err := os.MkdirAll(r.policy.ArchiveDir, 0o755)
+	if err != nil {
+		return result, err
+	}
+
+	for {
+		var logs []UserLog
+		err := r.db.WithContext(ctx).Where("created_at < ?", cutoff).
+			Order("created_at").Order("id").Limit(r.policy.BatchSize).Find(&logs).Error
+		if err != nil {
+			return result, err
+		}
+
+		if len(logs) == 0 {
+			break
+		}
+
+		// file archive di tulis dan di tutup terlebih dahulu, baru kemudian data di hapus dari database
+		// sehingga jika proses terhenti, data tidak akan hilang (paling buruk ter archive dua kali)
+		path, err := r.writeArchive(logs)
+		if err != nil {
+			return result, err
+		}
+		result.Files = append(result.Files, path)
+
+		ids := make([]interface{}, len(logs))
+		for i, log := range logs {
+			ids[i] = log.ID
+		}
+
+		err = r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&UserLog{}).Error
+		if err != nil {
+			return result, err
+		}
+
+		result.Archived += len(logs)
+	}
+
+	if r.policy.Partitioned {
+		err = r.EnablePartitioning(ctx)
+		if err != nil {
+			return result, err
+		}
+
+		dropped, err := r.DropExpiredPartitions(ctx, cutoff)
+		if err != nil {
+			return result, err
+		}
+		result.DroppedPartitions = dropped
+
+		err = r.EnsurePartitions(ctx)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// menulis satu batch user log ke file archive ndjson.gz
+func (r *UserLogRetention) writeArchive(logs []UserLog) (string, error) {
+	name := fmt.Sprintf("user_logs_%d_%d_%v.ndjson.gz", logs[0].CreatedAt, logs[len(logs)-1].CreatedAt, logs[0].ID)
+	path := filepath.Join(r.policy.ArchiveDir, name)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	writer := gzip.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, log := range logs {
+		err = encoder.Encode(log)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		return "", err
+	}
+
+	// memastikan file benar benar tersimpan di disk sebelum data di hapus
+	err = file.Sync()
+	if err != nil {
+		return "", err
+	}
+
+	return path, file.Close()
+}
+
+// mengembalikan isi file archive ke tabel user_logs
+// data yang id nya sudah ada di tabel akan dilewati, sehingga restore aman dijalankan berulang kali
+// archive lama (sebelum id integer dan action terdaftar) juga bisa di restore, lihat decodeArchivedUserLog()
+func RestoreUserLogArchive(ctx context.Context, db *gorm.DB, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return 0, err
+	}
+	defer reader.Close()
+
+	restored := 0
+	batch := make([]UserLog, 0, 500)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+
+		// created_at dan updated_at sudah ter isi dari archive, sehingga autoCreateTime tidak akan mengubah nya
+		result := db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&batch)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		restored += int(result.RowsAffected)
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		log, err := decodeArchivedUserLog(scanner.Bytes())
+		if err != nil {
+			return restored, err
+		}
+
+		batch = append(batch, log)
+		if len(batch) == cap(batch) {
+			err = flush()
+			if err != nil {
+				return restored, err
+			}
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return restored, err
+	}
+
+	return restored, flush()
+}
+
+// satu baris archive, id di archive lama masih berupa string (contoh "id":"5")
+type archivedUserLog struct {
+	UserLog
+	ID json.RawMessage
+}
+
+// membaca satu baris archive, baik format baru maupun format lama
+// - id string diubah menjadi integer, id lama yang bukan angka dikosongkan agar di isi auto increment
+// - action teks bebas diubah menjadi ActionLegacy, sama seperti pada MigrateUserLogs()
+func decodeArchivedUserLog(data []byte) (UserLog, error) {
+	var archived archivedUserLog
+	err := json.Unmarshal(data, &archived)
+	if err != nil {
+		return UserLog{}, err
+	}
+
+	log := archived.UserLog
+	id := strings.Trim(string(archived.ID), `"`)
+	if number, err := strconv.ParseInt(id, 10, 64); err == nil && number > 0 {
+		log.ID = number
+	}
+
+	return log, legacyUserLog(&log)
+}
+
+// mengubah tabel user_logs menjadi tabel yang di partisi per bulan (range partitioning, khusus mysql)
+// mysql mewajibkan kolom partisi menjadi bagian dari primary key, sehingga primary key diubah menjadi (id, created_at)
+func (r *UserLogRetention) EnablePartitioning(ctx context.Context) error {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return err
+	}
+	if len(partitions) > 0 {
+		return nil
+	}
+
+	db := r.db.WithContext(ctx)
+	err = db.Exec("ALTER TABLE user_logs DROP PRIMARY KEY, ADD PRIMARY KEY (id, created_at)").Error
+	if err != nil {
+		return err
+	}
+
+	var definitions []string
+	for _, month := range r.upcomingMonths() {
+		definitions = append(definitions, partitionDefinition(month))
+	}
+	definitions = append(definitions, "PARTITION pmax VALUES LESS THAN MAXVALUE")
+
+	return db.Exec("ALTER TABLE user_logs PARTITION BY RANGE (created_at) (" + strings.Join(definitions, ", ") + ")").Error
+}
+
+// menyiapkan partisi untuk bulan ini sampai MonthsAhead bulan ke depan
+// partisi baru dibuat dengan memecah partisi pmax (REORGANIZE PARTITION)
+func (r *UserLogRetention) EnsurePartitions(ctx context.Context) error {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return err
+	}
+
+	// partisi baru hanya bisa ditambahkan setelah batas atas partisi terakhir
+	var latest int64
+	for _, partition := range partitions {
+		upper, err := strconv.ParseInt(partition.Description, 10, 64)
+		if err == nil && upper > latest {
+			latest = upper
+		}
+	}
+
+	var definitions []string
+	for _, month := range r.upcomingMonths() {
+		if month.AddDate(0, 1, 0).UnixMilli() > latest {
+			definitions = append(definitions, partitionDefinition(month))
+		}
+	}
+
+	if len(definitions) == 0 {
+		return nil
+	}
+
+	definitions = append(definitions, "PARTITION pmax VALUES LESS THAN MAXVALUE")
+	return r.db.WithContext(ctx).Exec("ALTER TABLE user_logs REORGANIZE PARTITION pmax INTO (" + strings.Join(definitions, ", ") + ")").Error
+}
+
+// menghapus partisi yang seluruh isi nya lebih lama dari cutoff (batas atas partisi <= cutoff)
+func (r *UserLogRetention) DropExpiredPartitions(ctx context.Context, cutoff int64) ([]string, error) {
+	partitions, err := r.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var dropped []string
+	for _, partition := range partitions {
+		if partition.Name == "pmax" {
+			continue
+		}
+
+		upper, err := strconv.ParseInt(partition.Description, 10, 64)
+		if err != nil || upper > cutoff {
+			continue
+		}
+
+		err = r.db.WithContext(ctx).Exec("ALTER TABLE user_logs DROP PARTITION " + partition.Name).Error
+		if err != nil {
+			return dropped, err
+		}
+		dropped = append(dropped, partition.Name)
+	}
+
+	return dropped, nil
+}
+
+type userLogPartition struct {
+	Name        string `gorm:"column:name"`
+	Description string `gorm:"column:description"`
+}
+
+// mengambil daftar partisi tabel user_logs dari information_schema
+func (r *UserLogRetention) partitions(ctx context.Context) ([]userLogPartition, error) {
+	var partitions []userLogPartition
+	err := r.db.WithContext(ctx).Raw("SELECT PARTITION_NAME AS name, PARTITION_DESCRIPTION AS description " +
+		"FROM information_schema.PARTITIONS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'user_logs' " +
+		"AND PARTITION_NAME IS NOT NULL").Scan(&partitions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(partitions, func(i, j int) bool {
+		return partitions[i].Name < partitions[j].Name
+	})
+
+	return partitions, nil
+}
+
+// daftar awal bulan dari bulan ini sampai MonthsAhead bulan ke depan
+func (r *UserLogRetention) upcomingMonths() []time.Time {
+	now := r.policy.Now()
+	current := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	months := make([]time.Time, 0, r.policy.MonthsAhead+1)
+	for i := 0; i <= r.policy.MonthsAhead; i++ {
+		months = append(months, current.AddDate(0, i, 0))
+	}
+
+	return months
+}
+
+// nama partisi berdasarkan bulan, contoh p202601
+func partitionName(month time.Time) string {
+	return "p" + month.Format("200601")
+}
+
+// partisi berisi data dengan created_at sebelum awal bulan berikutnya
+func partitionDefinition(month time.Time) string {
+	return fmt.Sprintf("PARTITION %s VALUES LESS THAN (%d)", partitionName(month), month.AddDate(0, 1, 0).UnixMilli())
+}
@@ -5,7 +5,7 @@ import "time"
 type Wallet struct {
 	ID        string `gorm:"primary_key;column:id"`
 	UserId    string `gorm:"column:user_id"`
-	Balance   int64  `gorm:"column:balance"`
+	Balance   int64  `gorm:"column:balance;ledger"` // ledger : kolom saldo, data orphan nya tidak di hapus otomatis (lihat integrity.Plan)
 	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt time.Time `gorm:"column:updated_at;autoCreateTime;autoUpdateTime"`
 